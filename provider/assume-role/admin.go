@@ -0,0 +1,38 @@
+package assumerole
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// authorizeAdmin checks the bearer token of the admin endpoints.
+// The admin endpoints are disabled if the admin token is not configured.
+func (h *Handler) authorizeAdmin(w http.ResponseWriter, r *http.Request) error {
+	if len(h.adminToken) == 0 {
+		return &validationError{
+			message: "the admin endpoints are disabled",
+			code:    "NotFound",
+			status:  http.StatusNotFound,
+		}
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		return &validationError{
+			message: "method " + r.Method + " is not allowed, use GET",
+			code:    "MethodNotAllowed",
+			status:  http.StatusMethodNotAllowed,
+		}
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), h.adminToken) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		return &validationError{
+			message: "invalid admin token",
+			code:    "Unauthorized",
+			status:  http.StatusUnauthorized,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package assumerole
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	apiVersionV1 = "v1"
+	apiVersionV2 = "v2"
+)
+
+// responseBodyV2 is the response of /v2/assume-role and /v2/refresh.
+type responseBodyV2 struct {
+	APIVersion string `json:"api_version"`
+
+	// Credentials is nil in dry run mode.
+	Credentials  *credentialsV2 `json:"credentials,omitempty"`
+	RefreshToken string         `json:"refresh_token,omitempty"`
+
+	// Warnings is a list of warnings, it is never null.
+	Warnings []string `json:"warnings"`
+
+	// Explanation is available in dry run mode.
+	Explanation *explanation `json:"explanation,omitempty"`
+}
+
+type credentialsV2 struct {
+	AccessKeyID     string    `json:"access_key_id"`
+	SecretAccessKey string    `json:"secret_access_key"`
+	SessionToken    string    `json:"session_token"`
+	Expiration      time.Time `json:"expiration,omitzero"`
+}
+
+// serveAssumeRoleV2 is the versioned /assume-role.
+// The request is the same as v1, but unknown fields are always rejected,
+// so that new clients notice that the provider doesn't support their features.
+func (h *Handler) serveAssumeRoleV2(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var payload *requestBody
+	if err := h.decodeRequestWith(w, r, &payload, true); err != nil {
+		h.metrics.observeRequest("assume_role_v2", start, err)
+		h.writeError(w, r, err, apiVersionV2)
+		return
+	}
+
+	ctx, span := h.startSpan(h.extractSpanContext(r), "AssumeRole")
+	resp, err := h.handle(ctx, payload)
+	span.End(err)
+	h.metrics.observeRequest("assume_role_v2", start, err)
+	if err != nil {
+		h.writeError(w, r, err, apiVersionV2)
+		return
+	}
+	h.writeResponseV2(w, r, payload.OutputFormat, resp)
+}
+
+// serveRefreshV2 is the versioned /refresh.
+func (h *Handler) serveRefreshV2(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var payload *refreshRequestBody
+	if err := h.decodeRequestWith(w, r, &payload, true); err != nil {
+		h.metrics.observeRequest("refresh_v2", start, err)
+		h.writeError(w, r, err, apiVersionV2)
+		return
+	}
+
+	ctx, span := h.startSpan(h.extractSpanContext(r), "Refresh")
+	resp, err := h.refresh(ctx, payload)
+	span.End(err)
+	h.metrics.observeRequest("refresh_v2", start, err)
+	if err != nil {
+		h.writeError(w, r, err, apiVersionV2)
+		return
+	}
+	h.writeResponseV2(w, r, payload.OutputFormat, resp)
+}
+
+func (h *Handler) writeResponseV2(w http.ResponseWriter, r *http.Request, format string, resp *responseBody) {
+	if format == outputFormatCredentialProcess {
+		// the shape of credential_process is defined by the AWS SDKs, not by the API version.
+		h.writeResponse(w, r, format, resp)
+		return
+	}
+
+	body := &responseBodyV2{
+		APIVersion:   apiVersionV2,
+		RefreshToken: resp.RefreshToken,
+		Warnings:     splitWarnings(resp.Warning),
+		Explanation:  resp.Explanation,
+	}
+	if resp.Explanation == nil {
+		body.Credentials = &credentialsV2{
+			AccessKeyID:     resp.AccessKeyId,
+			SecretAccessKey: resp.SecretAccessKey,
+			SessionToken:    resp.SessionToken,
+			Expiration:      resp.Expiration,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.log().InfoContext(r.Context(), "failed to write the response", slog.String("error", err.Error()))
+	}
+}
+
+// splitWarnings splits the warnings of v1 responses, which are joined with newlines.
+func splitWarnings(warning string) []string {
+	warnings := []string{}
+	for line := range strings.SplitSeq(warning, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			warnings = append(warnings, line)
+		}
+	}
+	return warnings
+}
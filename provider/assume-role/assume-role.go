@@ -1,13 +1,16 @@
 package assumerole
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -19,8 +22,27 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sts/types"
 	"github.com/aws/smithy-go"
 	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
-	"github.com/shogo82148/aws-xray-yasdk-go/xrayaws-v2"
-	"github.com/shogo82148/aws-xray-yasdk-go/xrayhttp"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/metrics"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/ratelimit"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/tracing"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/trustpolicy"
+	"github.com/shogo82148/memoize"
+)
+
+const (
+	// outputFormatCredentialProcess renders the response in the shape of credential_process.
+	// https://docs.aws.amazon.com/sdkref/latest/guide/feature-process-credentials.html
+	outputFormatCredentialProcess = "credential_process"
+)
+
+const (
+	// the maximum duration of sessions.
+	// it is the default limit of role chaining.
+	maxDurationSeconds = 60 * 60
+
+	// the default limit of the size of request bodies.
+	// it is large enough for OIDC tokens and trust policy documents.
+	defaultMaxRequestBodySize = 64 << 10
 )
 
 const (
@@ -30,20 +52,48 @@ const (
 	creatorType         = "Bot"
 )
 
-type githubClient interface {
+// GitHubClient is the interface of GitHub API used by Handler.
+// *github.Client implements it.
+type GitHubClient interface {
 	CreateStatus(ctx context.Context, token, owner, repo, ref string, status *github.CreateStatusRequest) (*github.CreateStatusResponse, error)
+	CreateCheckRun(ctx context.Context, token, owner, repo string, run *github.CreateCheckRunRequest) (*github.CreateCheckRunResponse, error)
 	GetRepo(ctx context.Context, nextIDFormat bool, token, owner, repo string) (*github.GetRepoResponse, error)
 	GetUser(ctx context.Context, nextIDFormat bool, token, user string) (*github.GetUserResponse, error)
+	GetEnvironment(ctx context.Context, token, owner, repo, environment string) (*github.GetEnvironmentResponse, error)
+	ListDeployments(ctx context.Context, token, owner, repo, sha, environment string) ([]*github.Deployment, error)
+	ListWorkflowRunApprovals(ctx context.Context, token, owner, repo, runID string) ([]*github.WorkflowRunApproval, error)
 	ValidateAPIURL(url string) error
 	ParseIDToken(ctx context.Context, idToken string) (*github.ActionsIDToken, error)
+	CheckJWKS(ctx context.Context) error
+	Ping(ctx context.Context) error
+}
+
+// GitHubTokenSource provides tokens for GitHub API calls made by the provider itself,
+// e.g. GitHub App installation tokens.
+type GitHubTokenSource interface {
+	// Token returns a token that can access the repository.
+	Token(ctx context.Context, owner, repo string) (string, error)
 }
 
-type stsClient interface {
+var _ GitHubTokenSource = (*github.AppTokenSource)(nil)
+
+// STSClient is the interface of AWS STS API used by Handler.
+// *sts.Client implements it.
+type STSClient interface {
 	AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error)
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
 }
 
 type validationError struct {
 	message string
+
+	// code is a short code of the error for metrics.
+	// If it is empty, "ValidationError" is used.
+	code string
+
+	// status is the status code of the response.
+	// If it is zero, 400 Bad Request is used.
+	status int
 }
 
 func (err *validationError) Error() string {
@@ -51,31 +101,358 @@ func (err *validationError) Error() string {
 }
 
 type Handler struct {
-	github githubClient
-	sts    stsClient
+	github GitHubClient
+	sts    STSClient
+
+	// the secret key for signing refresh tokens.
+	// refreshing credentials is disabled if it is empty.
+	refreshSecret []byte
+
+	// policies restrict which repositories can assume which roles.
+	policies []Policy
+
+	// deploymentGates require approved deployments for assuming the roles.
+	deploymentGates []DeploymentGate
+
+	// the cache of health check results.
+	healthCache memoize.Group[string, *healthCheckResult]
+
+	// the cache of the principal of the credential provider.
+	principalCache memoize.Group[struct{}, string]
+
+	// metrics is nil if metrics are disabled.
+	metrics *handlerMetrics
+
+	// tracer is nil if tracing is disabled.
+	tracer tracing.Tracer
+
+	// logger is nil if slog.Default() is used.
+	logger *slog.Logger
+
+	// clock is nil if time.Now is used.
+	clock func() time.Time
+
+	// hooks are called around issuing credentials.
+	hooks []Hook
+
+	// commitStatus reports issuances as commit statuses.
+	commitStatus *commitStatusReporter
+
+	// checkRun reports failures as check runs.
+	checkRun *CheckRunConfig
+
+	// githubTokens provides tokens for GitHub API calls made by the provider itself.
+	githubTokens GitHubTokenSource
+
+	// probeStrategies detect trust policies that are too open.
+	// If it is empty, defaultProbeStrategies are used.
+	probeStrategies []ProbeStrategy
+
+	// probeCache is nil if caching the probe results is disabled.
+	probeCache *probeCache
+
+	// iam fetches the trust policies of roles.
+	// It is nil if fetching is disabled.
+	iam trustpolicy.IAMClient
+
+	// rateLimits limit the rate of requests.
+	// Rate limiting is disabled if it is empty.
+	rateLimits []RateLimit
+
+	// rateLimitStore stores the token buckets of rateLimits.
+	rateLimitStore ratelimit.Store
+
+	// maxRequestBodySize is the limit of the size of request bodies.
+	// If it is zero, defaultMaxRequestBodySize is used.
+	maxRequestBodySize int64
+
+	// strictDecoding rejects unknown fields in request bodies.
+	strictDecoding bool
+
+	// githubTokenAuth restricts the authentication with GITHUB_TOKEN.
+	// If it is nil, all repositories can use GITHUB_TOKEN.
+	githubTokenAuth *GitHubTokenAuth
+
+	// legacyNodeIDs records the usages of the legacy node IDs.
+	legacyNodeIDs LegacyNodeIDStore
+
+	// adminToken is the bearer token for the admin endpoints.
+	// The admin endpoints are disabled if it is empty.
+	adminToken []byte
 }
 
-func NewHandler() *Handler {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// Config is configure for Handler.
+// Each field corresponds to an Option.
+type Config struct {
+	// HTTPClient is used for requests to GitHub.
+	// If it is nil, the client instrumented by the tracer is used.
+	HTTPClient *http.Client
+
+	// GitHubAPIURL is the URL of GitHub API.
+	// If it is empty, the value of GITHUB_API_URL environment value or https://api.github.com is used.
+	GitHubAPIURL string
+
+	// GitHubOIDCIssuer is the issuer of OIDC tokens.
+	// If it is empty, https://token.actions.githubusercontent.com is used.
+	GitHubOIDCIssuer string
+
+	// RefreshSecret is the secret key for signing refresh tokens.
+	// Refreshing credentials is disabled if it is empty.
+	RefreshSecret []byte
+
+	// Policies restrict which repositories can assume which roles.
+	Policies []Policy
+
+	// DeploymentGates require approved deployments for assuming the roles.
+	DeploymentGates []DeploymentGate
 
-	cfg, err := config.LoadDefaultConfig(ctx, xrayaws.WithXRay())
+	// Metrics is the registry for the metrics of the handler.
+	// Metrics are disabled if it is nil.
+	Metrics *metrics.Registry
+
+	// Tracer is the tracing backend.
+	// If it is nil, AWS X-Ray is used.
+	Tracer tracing.Tracer
+
+	// Hooks are called around issuing credentials.
+	Hooks []Hook
+
+	// CommitStatus enables reporting each issuance as a commit status.
+	// It is disabled if it is nil.
+	CommitStatus *CommitStatusConfig
+
+	// CheckRun enables reporting failures as check runs.
+	// It is disabled if it is nil.
+	CheckRun *CheckRunConfig
+
+	// GitHubTokenSource provides tokens for GitHub API calls made by the provider itself.
+	// If it is nil, GITHUB_TOKEN of requests is used.
+	GitHubTokenSource GitHubTokenSource
+
+	// ProbeStrategies detect trust policies that are too open.
+	// If it is empty, ProbeNoExternalID and ProbeRandomExternalID are used.
+	ProbeStrategies []ProbeStrategy
+
+	// ProbeCacheTTL is how long the passed results of the probes are cached
+	// per role and the shape of the session tags.
+	// Caching is disabled if it is zero.
+	ProbeCacheTTL time.Duration
+
+	// IAMClient fetches the trust policies of roles for the trust policy linter.
+	// If it is nil, the linter accepts only the documents in requests.
+	IAMClient trustpolicy.IAMClient
+
+	// RateLimits limit the rate of requests per repository, owner or role.
+	// Rate limiting is disabled if it is empty.
+	RateLimits []RateLimit
+
+	// RateLimitStore stores the token buckets of RateLimits.
+	// If it is nil, the buckets are stored in memory of the process.
+	RateLimitStore ratelimit.Store
+
+	// MaxRequestBodySize is the limit of the size of request bodies in bytes.
+	// If it is zero, 64 KiB is used.
+	MaxRequestBodySize int64
+
+	// StrictDecoding rejects unknown fields in request bodies,
+	// so that typos such as "role_to_asume" are reported.
+	StrictDecoding bool
+
+	// GitHubTokenAuth restricts the deprecated authentication with GITHUB_TOKEN.
+	// If it is nil, all repositories can use GITHUB_TOKEN.
+	GitHubTokenAuth *GitHubTokenAuth
+
+	// LegacyNodeIDStore records the repositories and roles that are authorized only by the legacy node IDs.
+	// If it is nil, they are stored in memory of the process.
+	LegacyNodeIDStore LegacyNodeIDStore
+
+	// AdminToken is the bearer token for the admin endpoints.
+	// The admin endpoints are disabled if it is empty.
+	AdminToken []byte
+}
+
+// NewHandler returns a new handler that is configured by the environment values.
+// It panics if the configuration is invalid.
+func NewHandler() *Handler {
+	tracer, err := tracing.NewFromEnv()
 	if err != nil {
-		slog.ErrorContext(ctx, "unable to load SDK config", slog.String("error", err.Error()))
 		panic(err)
 	}
-
-	client := xrayhttp.Client(nil)
-	githubClient, err := github.NewClient(client)
+	h, err := NewHandlerWithOptions(
+		WithRefreshSecret([]byte(os.Getenv("REFRESH_TOKEN_SECRET"))),
+		WithTracer(tracer),
+	)
 	if err != nil {
-		slog.ErrorContext(ctx, "unable to create github client", slog.String("error", err.Error()))
 		panic(err)
 	}
+	return h
+}
+
+// NewHandlerWithConfig returns a new handler.
+// It is a shorthand of NewHandlerWithOptions with the options that correspond to the fields of c.
+// ctx is used while creating the clients.
+func NewHandlerWithConfig(ctx context.Context, c *Config) (*Handler, error) {
+	return NewHandlerWithOptions(append(c.options(), withContext(ctx))...)
+}
+
+// options translates the config into the options.
+func (c *Config) options() []Option {
+	return []Option{
+		WithHTTPClient(c.HTTPClient),
+		WithGitHubAPIURL(c.GitHubAPIURL),
+		WithGitHubOIDCIssuer(c.GitHubOIDCIssuer),
+		WithRefreshSecret(c.RefreshSecret),
+		WithPolicies(c.Policies),
+		WithDeploymentGates(c.DeploymentGates),
+		WithMetrics(c.Metrics),
+		WithTracer(c.Tracer),
+		WithHooks(c.Hooks...),
+		WithCommitStatus(c.CommitStatus),
+		WithCheckRun(c.CheckRun),
+		WithGitHubTokenSource(c.GitHubTokenSource),
+		WithIAMClient(c.IAMClient),
+		WithProbeStrategies(c.ProbeStrategies...),
+		WithProbeCacheTTL(c.ProbeCacheTTL),
+		WithRateLimits(c.RateLimits...),
+		WithRateLimitStore(c.RateLimitStore),
+		WithMaxRequestBodySize(c.MaxRequestBodySize),
+		WithStrictDecoding(c.StrictDecoding),
+		WithGitHubTokenAuth(c.GitHubTokenAuth),
+		WithLegacyNodeIDStore(c.LegacyNodeIDStore),
+		WithAdminToken(c.AdminToken),
+	}
+}
+
+// NewHandlerWithOptions returns a new handler.
+// Without options, it is configured in the same way as NewHandlerWithConfig with the zero Config.
+func NewHandlerWithOptions(opts ...Option) (*Handler, error) {
+	var o handlerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx := o.ctx
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+	}
+
+	logger := o.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	tracer := o.tracer
+	if tracer == nil {
+		tracer = tracing.XRay()
+	}
+	if err := validateProbeStrategies(o.probeStrategies); err != nil {
+		return nil, err
+	}
+	if err := validateRateLimits(o.rateLimits); err != nil {
+		return nil, err
+	}
+	rateLimitStore := o.rateLimitStore
+	if rateLimitStore == nil && len(o.rateLimits) > 0 {
+		rateLimitStore = ratelimit.NewMemoryStore()
+	}
+	legacyNodeIDs := o.legacyNodeIDStore
+	if legacyNodeIDs == nil {
+		legacyNodeIDs = NewMemoryLegacyNodeIDStore()
+	}
+	m := newHandlerMetrics(o.metrics)
+	commitStatus, err := newCommitStatusReporter(o.commitStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	factory := o.stsClientFactory
+	if factory == nil {
+		factory = defaultSTSClientFactory(tracer)
+	}
+	stsClient, err := factory(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "unable to create sts client", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	githubClient := o.githubClient
+	if githubClient == nil {
+		client := o.httpClient
+		if client == nil {
+			client = tracer.HTTPClient(nil)
+		}
+		client = m.instrumentHTTPClient(client)
+		githubClient, err = github.NewClientWithConfig(&github.ClientConfig{
+			HTTPClient: client,
+			APIURL:     o.githubAPIURL,
+			OIDCIssuer: o.githubOIDCIssuer,
+		})
+		if err != nil {
+			logger.ErrorContext(ctx, "unable to create github client", slog.String("error", err.Error()))
+			return nil, err
+		}
+	}
+
+	githubClient = &githubClientWithTracing{GitHubClient: githubClient, tracer: tracer}
+	stsClient = &stsClientWithTracing{STSClient: stsClient, tracer: tracer}
+	if m != nil {
+		githubClient = &githubClientWithMetrics{GitHubClient: githubClient, metrics: m}
+		stsClient = &stsClientWithMetrics{STSClient: stsClient, metrics: m}
+	}
 
 	return &Handler{
-		github: githubClient,
-		sts:    sts.NewFromConfig(cfg),
+		github:             githubClient,
+		sts:                stsClient,
+		refreshSecret:      o.refreshSecret,
+		policies:           o.policies,
+		deploymentGates:    o.deploymentGates,
+		metrics:            m,
+		tracer:             tracer,
+		logger:             o.logger,
+		clock:              o.clock,
+		hooks:              o.hooks,
+		commitStatus:       commitStatus,
+		checkRun:           o.checkRun,
+		githubTokens:       o.githubTokens,
+		iam:                o.iamClient,
+		probeStrategies:    o.probeStrategies,
+		probeCache:         newProbeCache(o.probeCacheTTL),
+		rateLimits:         o.rateLimits,
+		rateLimitStore:     rateLimitStore,
+		maxRequestBodySize: o.maxRequestBodySize,
+		strictDecoding:     o.strictDecoding,
+		githubTokenAuth:    o.githubTokenAuth,
+		legacyNodeIDs:      legacyNodeIDs,
+		adminToken:         o.adminToken,
+	}, nil
+}
+
+// defaultSTSClientFactory loads the default configuration of the AWS SDK.
+func defaultSTSClientFactory(tracer tracing.Tracer) STSClientFactory {
+	return func(ctx context.Context) (STSClient, error) {
+		cfg, err := config.LoadDefaultConfig(ctx, tracer.AWSConfigOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SDK config: %w", err)
+		}
+		return sts.NewFromConfig(cfg), nil
+	}
+}
+
+// log returns the logger of the handler.
+func (h *Handler) log() *slog.Logger {
+	if h.logger == nil {
+		return slog.Default()
+	}
+	return h.logger
+}
+
+// now returns the current time of the handler's clock.
+func (h *Handler) now() time.Time {
+	if h.clock == nil {
+		return time.Now()
 	}
+	return h.clock()
 }
 
 type requestBody struct {
@@ -93,45 +470,211 @@ type requestBody struct {
 	Workflow           string `json:"workflow"`
 	Actor              string `json:"actor"`
 	Branch             string `json:"branch"`
+	OutputFormat       string `json:"output_format"`
+
+	// DryRun explains how the role would be assumed without issuing credentials.
+	DryRun bool `json:"dry_run"`
+
+	// Probe runs the negative ExternalId probe in dry run mode.
+	// The probe always runs when credentials are issued.
+	Probe bool `json:"probe"`
 }
 
 type responseBody struct {
-	AccessKeyId     string `json:"access_key_id"`
-	SecretAccessKey string `json:"secret_access_key"`
-	SessionToken    string `json:"session_token"`
-	Message         string `json:"message,omitempty"`
-	Warning         string `json:"warning,omitempty"`
+	AccessKeyId     string    `json:"access_key_id"`
+	SecretAccessKey string    `json:"secret_access_key"`
+	SessionToken    string    `json:"session_token"`
+	Expiration      time.Time `json:"expiration,omitzero"`
+	RefreshToken    string    `json:"refresh_token,omitempty"`
+	Message         string    `json:"message,omitempty"`
+	Warning         string    `json:"warning,omitempty"`
+
+	// Explanation is available in dry run mode.
+	Explanation *explanation `json:"explanation,omitempty"`
+}
+
+// credentialProcessResponseBody is the response in the shape of credential_process.
+// https://docs.aws.amazon.com/sdkref/latest/guide/feature-process-credentials.html
+type credentialProcessResponseBody struct {
+	Version         int        `json:"Version"`
+	AccessKeyId     string     `json:"AccessKeyId"`
+	SecretAccessKey string     `json:"SecretAccessKey"`
+	SessionToken    string     `json:"SessionToken"`
+	Expiration      *time.Time `json:"Expiration,omitempty"`
 }
 
 type errorResponseBody struct {
 	Message string `json:"message"`
+
+	// APIVersion and Code are available in the versioned API.
+	APIVersion string `json:"api_version,omitempty"`
+	Code       string `json:"code,omitempty"`
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	switch r.URL.Path {
+	case "/healthz":
+		h.serveHealthz(w, r)
+	case "/readyz":
+		h.serveReadyz(w, r)
+	case "/refresh":
+		h.serveRefresh(w, r)
+	case "/trust-policy":
+		h.serveTrustPolicy(w, r)
+	case "/lint-trust-policy":
+		h.serveLintTrustPolicy(w, r)
+	case "/capabilities":
+		h.serveCapabilities(w, r)
+	case "/v2/assume-role":
+		h.serveAssumeRoleV2(w, r)
+	case "/v2/refresh":
+		h.serveRefreshV2(w, r)
+	case "/admin/legacy-node-ids":
+		h.serveLegacyNodeIDs(w, r)
+	default:
+		// for backward compatibility, all other paths are treated as /assume-role.
+		h.serveAssumeRole(w, r)
+	}
+}
+
+func (h *Handler) serveAssumeRole(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var payload *requestBody
+	if err := h.decodeRequest(w, r, &payload); err != nil {
+		h.metrics.observeRequest("assume_role", start, err)
+		h.handleError(w, r, err)
+		return
+	}
 
-	data, err := io.ReadAll(r.Body)
+	ctx, span := h.startSpan(h.extractSpanContext(r), "AssumeRole")
+	resp, err := h.handle(ctx, payload)
+	span.End(err)
+	h.metrics.observeRequest("assume_role", start, err)
 	if err != nil {
-		h.handleError(w, r, fmt.Errorf("failed to read the request body: %w", err))
+		h.handleError(w, r, err)
 		return
 	}
-	var payload *requestBody
-	if err := json.Unmarshal(data, &payload); err != nil {
-		h.handleError(w, r, &validationError{
-			message: fmt.Sprintf("failed to unmarshal the request body: %v", err),
-		})
+	h.writeResponse(w, r, payload.OutputFormat, resp)
+}
+
+func (h *Handler) serveRefresh(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var payload *refreshRequestBody
+	if err := h.decodeRequest(w, r, &payload); err != nil {
+		h.metrics.observeRequest("refresh", start, err)
+		h.handleError(w, r, err)
 		return
 	}
 
-	resp, err := h.handle(ctx, payload)
+	ctx, span := h.startSpan(h.extractSpanContext(r), "Refresh")
+	resp, err := h.refresh(ctx, payload)
+	span.End(err)
+	h.metrics.observeRequest("refresh", start, err)
 	if err != nil {
 		h.handleError(w, r, err)
 		return
 	}
+	h.writeResponse(w, r, payload.OutputFormat, resp)
+}
+
+// decodeRequest decodes the JSON body of POST requests.
+func (h *Handler) decodeRequest(w http.ResponseWriter, r *http.Request, v any) error {
+	return h.decodeRequestWith(w, r, v, h.strictDecoding)
+}
+
+func (h *Handler) decodeRequestWith(w http.ResponseWriter, r *http.Request, v any, strict bool) error {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		return &validationError{
+			message: fmt.Sprintf("method %s is not allowed, use POST", r.Method),
+			code:    "MethodNotAllowed",
+			status:  http.StatusMethodNotAllowed,
+		}
+	}
+	if err := validateContentType(r.Header.Get("Content-Type")); err != nil {
+		return err
+	}
+
+	limit := h.maxRequestBodySize
+	if limit <= 0 {
+		limit = defaultMaxRequestBodySize
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return &validationError{
+				message: fmt.Sprintf("the request body is too large, the limit is %d bytes", tooLarge.Limit),
+				code:    "RequestEntityTooLarge",
+				status:  http.StatusRequestEntityTooLarge,
+			}
+		}
+		return fmt.Errorf("failed to read the request body: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		// reject typos such as "role_to_asume".
+		dec.DisallowUnknownFields()
+	}
+	err = dec.Decode(v)
+	if errors.Is(err, io.EOF) {
+		err = errors.New("unexpected end of JSON input")
+	} else if err == nil {
+		// same as json.Unmarshal, the body must have exactly one value.
+		if _, err = dec.Token(); errors.Is(err, io.EOF) {
+			err = nil
+		} else if err == nil {
+			err = errors.New("invalid character after top-level value")
+		}
+	}
+	if err != nil {
+		verr := &validationError{
+			message: fmt.Sprintf("failed to unmarshal the request body: %v", err),
+		}
+		if strings.HasPrefix(err.Error(), "json: unknown field ") {
+			verr.code = "UnknownField"
+		}
+		return verr
+	}
+	return nil
+}
+
+// validateContentType accepts JSON media types.
+// For backward compatibility, requests without Content-Type are also accepted.
+func validateContentType(contentType string) error {
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+	return &validationError{
+		message: fmt.Sprintf("unsupported content type %q, use application/json", contentType),
+		code:    "UnsupportedMediaType",
+		status:  http.StatusUnsupportedMediaType,
+	}
+}
+
+func (h *Handler) writeResponse(w http.ResponseWriter, r *http.Request, format string, resp *responseBody) {
+	var body any = resp
+	if format == outputFormatCredentialProcess {
+		cp := &credentialProcessResponseBody{
+			Version:         1,
+			AccessKeyId:     resp.AccessKeyId,
+			SecretAccessKey: resp.SecretAccessKey,
+			SessionToken:    resp.SessionToken,
+		}
+		if !resp.Expiration.IsZero() {
+			cp.Expiration = &resp.Expiration
+		}
+		body = cp
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		slog.InfoContext(ctx, "failed to write the response", slog.String("error", err.Error()))
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.log().InfoContext(r.Context(), "failed to write the response", slog.String("error", err.Error()))
 	}
 }
 
@@ -144,6 +687,7 @@ func (h *Handler) handle(ctx context.Context, req *requestBody) (*responseBody,
 
 	var idToken *github.ActionsIDToken
 	if req.IDToken != "" {
+		h.metrics.observeCredentialType("oidc")
 		var err error
 		idToken, err = h.github.ParseIDToken(ctx, req.IDToken)
 		if err != nil {
@@ -151,35 +695,151 @@ func (h *Handler) handle(ctx context.Context, req *requestBody) (*responseBody,
 				message: fmt.Sprintf("invalid oidc token: %v", err),
 			}
 		}
+		if err := bindIDToken(req, idToken); err != nil {
+			return nil, err
+		}
 	} else {
-		slog.InfoContext(ctx, "OIDC token is not available")
+		h.log().InfoContext(ctx, "OIDC token is not available")
+		h.metrics.observeCredentialType("github_token")
+		sunset, err := h.checkGitHubTokenAuth(ctx, req)
+		if err != nil {
+			return nil, err
+		}
 		warning += "Using GITHUB_TOKEN is deprecated. Use OIDC instead of it. " +
-			"See https://github.com/fuller-inc/actions-aws-assume-role/issues/454 and https://docs.github.com/en/actions/deployment/security-hardening-your-deployments/about-security-hardening-with-openid-connect .\n"
+			"See https://github.com/fuller-inc/actions-aws-assume-role/issues/454 and https://docs.github.com/en/actions/deployment/security-hardening-your-deployments/about-security-hardening-with-openid-connect .\n" +
+			sunset
 		if err := h.validateGitHubToken(ctx, req); err != nil {
 			return nil, err
 		}
+		// the owner is verified by the commit status.
+		owner, _, _ := strings.Cut(req.Repository, "/")
+		h.metrics.observeGitHubToken(owner, "accepted")
+	}
+
+	repository := req.Repository
+	if idToken != nil {
+		repository = idToken.Repository
+	}
+	if err := h.checkRateLimit(ctx, repository, req.RoleToAssume); err != nil {
+		return nil, err
+	}
+
+	if req.DryRun {
+		resp, err := h.explain(ctx, idToken, req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Warning = warning
+		return resp, nil
+	}
+
+	resp, err := h.issue(ctx, idToken, req)
+	if err != nil {
+		h.reportCheckRun(ctx, idToken, req, err)
+		return nil, err
+	}
+	resp.Warning = warning + resp.Warning
+	return resp, nil
+}
+
+// issue authorizes the authenticated request and assumes the role.
+func (h *Handler) issue(ctx context.Context, idToken *github.ActionsIDToken, req *requestBody) (*responseBody, error) {
+	return h.issueWith(ctx, idToken, req, func(ctx context.Context) (*responseBody, error) {
+		return h.assumeRoleWithFallback(ctx, idToken, req)
+	})
+}
+
+// issueWith authorizes the authenticated request and issues the credentials by assume.
+// The policies, the deployment gates, the hooks and the commit statuses are applied
+// in the same way for both new credentials and refreshed ones.
+func (h *Handler) issueWith(ctx context.Context, idToken *github.ActionsIDToken, req *requestBody, assume func(ctx context.Context) (*responseBody, error)) (*responseBody, error) {
+	repository := req.Repository
+	if idToken != nil {
+		repository = idToken.Repository
+	}
+	authCtx, span := h.startSpan(ctx, "Authorize",
+		tracing.String("github.repository", repository),
+		tracing.String("aws.iam.role_arn", req.RoleToAssume),
+		tracing.Bool("github.oidc", idToken != nil),
+		tracing.Bool("github.use_node_id", req.UseNodeID),
+	)
+	err := h.authorize(repository, req.RoleToAssume, req.DurationSeconds)
+	if err == nil {
+		err = h.checkDeployment(authCtx, idToken, req)
+	}
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+
+	issuance := &Issuance{
+		IDToken:         idToken,
+		Repository:      repository,
+		RoleARN:         req.RoleToAssume,
+		RoleSessionName: req.RoleSessionName,
+		DurationSeconds: req.DurationSeconds,
+	}
+	if err := h.beforeIssue(ctx, issuance); err != nil {
+		return nil, err
+	}
+
+	resp, err := assume(ctx)
+	if err != nil {
+		result := &IssueResult{Err: err}
+		h.afterIssue(ctx, issuance, result)
+		h.reportCommitStatus(ctx, idToken, req, result)
+		return nil, err
+	}
+	result := &IssueResult{Expiration: resp.Expiration}
+	h.afterIssue(ctx, issuance, result)
+	h.reportCommitStatus(ctx, idToken, req, result)
+	resp.Warning = issuance.warning() + resp.Warning
+	return resp, nil
+}
+
+// assumeRoleWithFallback assumes the role with the next ID format,
+// and falls back to the legacy ID format if the request uses node IDs.
+// The node IDs of the legacy format are looked up while trying the next format,
+// so that the fallback doesn't wait for GitHub API.
+func (h *Handler) assumeRoleWithFallback(ctx context.Context, idToken *github.ActionsIDToken, req *requestBody) (*responseBody, error) {
+	var legacy <-chan assumeRoleInputResult
+	if req.UseNodeID {
+		// the lookup is canceled if the next format succeeds.
+		legacyCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		legacy = h.assumeRoleInputAsync(legacyCtx, false, idToken, req)
 	}
 
 	// Use Next ID format
-	resp0, err0 := h.assumeRole(ctx, true, idToken, req)
+	input0, err0 := h.assumeRoleInput(ctx, true, idToken, req)
 	if err0 == nil {
-		resp0.Warning += warning
-		return resp0, nil
+		var resp0 *responseBody
+		resp0, err0 = h.assumeRoleWithInput(ctx, idToken, input0)
+		if err0 == nil {
+			return resp0, nil
+		}
 	}
 	if !req.UseNodeID {
 		return nil, err0
 	}
 
 	// Use legacy or next ID format
-	resp1, err1 := h.assumeRole(ctx, false, idToken, req)
+	// The error of the next format is reported, because the legacy format is deprecated.
+	result := <-legacy
+	if result.err != nil {
+		return nil, err0
+	}
+	input1 := result.input
+	resp1, err1 := h.assumeRoleWithInput(ctx, idToken, input1)
 	if err1 != nil {
 		return nil, err0
 	}
-	resp1.Warning += warning
 	resp1.Warning += "It looks that you use legacy node IDs. You need to migrate them. " +
 		"See https://github.com/fuller-inc/actions-aws-assume-role#migrate-your-node-id-to-the-next-format for more detail.\n" +
 		err0.Error()
-	slog.InfoContext(ctx, "using legacy node id")
+	h.log().InfoContext(ctx, "using legacy node id")
+	h.metrics.observeLegacyNodeID()
+	h.recordLegacyNodeID(ctx, req, input1, input0)
 	return resp1, nil
 }
 
@@ -197,6 +857,35 @@ func (h *Handler) validate(ctx context.Context, req *requestBody) error {
 			message: "missing required input: role-session-name",
 		}
 	}
+	if req.IDToken == "" {
+		// the OIDC token has them as its claims.
+		if err := validateEnvironmentValues(req); err != nil {
+			return err
+		}
+	}
+	if req.DurationSeconds <= 0 || req.DurationSeconds > maxDurationSeconds {
+		return &validationError{
+			message: fmt.Sprintf("invalid role-duration-seconds %d, it should be from 1 to %d", req.DurationSeconds, maxDurationSeconds),
+		}
+	}
+	if err := validateOutputFormat(req.OutputFormat); err != nil {
+		return err
+	}
+	if req.DryRun && req.OutputFormat != "" {
+		return &validationError{
+			message: "dry_run is not available with output_format",
+		}
+	}
+	if req.Probe && !req.DryRun {
+		return &validationError{
+			message: "probe is available only in dry_run mode",
+		}
+	}
+	return nil
+}
+
+// validateEnvironmentValues checks the fields that GITHUB_TOKEN requests need.
+func validateEnvironmentValues(req *requestBody) error {
 	if req.Repository == "" {
 		return &validationError{
 			message: "Missing required environment value: GITHUB_REPOSITORY",
@@ -222,32 +911,68 @@ func (h *Handler) validate(ctx context.Context, req *requestBody) error {
 			message: "Missing required environment value: GITHUB_ACTOR",
 		}
 	}
-	if req.DurationSeconds <= 0 || req.DurationSeconds > 60*60 {
-		return &validationError{
-			message: fmt.Sprintf("invalid role-duration-seconds %d, it should be from 1 to 3600", req.DurationSeconds),
-		}
-	}
 	return nil
 }
 
+func validateOutputFormat(format string) error {
+	switch format {
+	case "", outputFormatCredentialProcess:
+		return nil
+	}
+	return &validationError{
+		message: fmt.Sprintf("unknown output_format: %q", format),
+	}
+}
+
 func (h *Handler) handleError(w http.ResponseWriter, r *http.Request, err error) {
-	slog.ErrorContext(r.Context(), "failed to handle the request", slog.String("error", err.Error()))
+	h.writeError(w, r, err, "")
+}
+
+// writeError writes the error response.
+// The responses of the versioned API have the version and the code of the error.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error, apiVersion string) {
+	h.log().ErrorContext(r.Context(), "failed to handle the request", slog.String("error", err.Error()))
 	status := http.StatusInternalServerError
 	var body *errorResponseBody
 
 	var validation *validationError
 	if errors.As(err, &validation) {
 		status = http.StatusBadRequest
+		if validation.status != 0 {
+			status = validation.status
+		}
 		body = &errorResponseBody{
 			Message: validation.message,
 		}
 	}
+	var veto *VetoError
+	if errors.As(err, &veto) {
+		status = http.StatusForbidden
+		body = &errorResponseBody{
+			Message: veto.Message,
+		}
+	}
+	var rateLimit *RateLimitError
+	if errors.As(err, &rateLimit) {
+		status = http.StatusTooManyRequests
+		body = &errorResponseBody{
+			Message: rateLimit.Error(),
+		}
+		w.Header().Set("Retry-After", rateLimit.retryAfterSeconds())
+	}
 
 	if body == nil {
 		body = &errorResponseBody{
 			Message: "Internal Server Error",
 		}
 	}
+	if apiVersion != "" {
+		body.APIVersion = apiVersion
+		body.Code = "InternalError"
+		if status != http.StatusInternalServerError {
+			body.Code = errorCode(err)
+		}
+	}
 	data, err := json.Marshal(body)
 	if err != nil {
 		panic(err)
@@ -338,6 +1063,39 @@ func (h *Handler) updateCommitStatus(ctx context.Context, req *requestBody, stat
 	return h.github.CreateStatus(ctx, req.GitHubToken, owner, repo, req.SHA, status)
 }
 
+// lookupRepoAndUser gets the repository and the actor from GitHub concurrently.
+// If one of them fails, the other is canceled.
+// The error of the repository is reported first, in the same way as looking them up in order.
+func (h *Handler) lookupRepoAndUser(ctx context.Context, nextIDFormat bool, idToken *github.ActionsIDToken, req *requestBody) (*github.GetRepoResponse, *github.GetUserResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var user *github.GetUserResponse
+	var userErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		user, userErr = h.getUser(ctx, nextIDFormat, idToken, req)
+		if userErr != nil {
+			cancel()
+		}
+	}()
+	repo, repoErr := h.getRepo(ctx, nextIDFormat, idToken, req)
+	if repoErr != nil {
+		cancel()
+	}
+	<-done
+
+	// the lookup of the repository may be canceled by the failure of the user.
+	if repoErr != nil && (userErr == nil || !errors.Is(repoErr, context.Canceled)) {
+		return nil, nil, repoErr
+	}
+	if userErr != nil {
+		return nil, nil, userErr
+	}
+	return repo, user, nil
+}
+
 func (h *Handler) getRepo(ctx context.Context, nextIDFormat bool, idToken *github.ActionsIDToken, req *requestBody) (*github.GetRepoResponse, error) {
 	var owner, repo string
 	var err error
@@ -351,25 +1109,84 @@ func (h *Handler) getRepo(ctx context.Context, nextIDFormat bool, idToken *githu
 	if err != nil {
 		return nil, err
 	}
-	return h.github.GetRepo(ctx, nextIDFormat, req.GitHubToken, owner, repo)
+	return h.github.GetRepo(ctx, nextIDFormat, h.githubToken(ctx, owner, repo, req), owner, repo)
 }
 
 func (h *Handler) getUser(ctx context.Context, nextIDFormat bool, idToken *github.ActionsIDToken, req *requestBody) (*github.GetUserResponse, error) {
+	repository, actor := req.Repository, req.Actor
 	if idToken != nil {
 		// Get the information from the id token if it's available.
 		// They are more trustworthy because they are digitally signed.
-		return h.github.GetUser(ctx, nextIDFormat, req.GitHubToken, idToken.Actor)
-	} else {
-		return h.github.GetUser(ctx, nextIDFormat, req.GitHubToken, req.Actor)
+		repository, actor = idToken.Repository, idToken.Actor
 	}
+	owner, repo, err := splitOwnerRepo(repository)
+	if err != nil {
+		return nil, err
+	}
+	return h.github.GetUser(ctx, nextIDFormat, h.githubToken(ctx, owner, repo, req), actor)
+}
+
+// githubToken returns the token for GitHub API calls that the provider makes for its own lookups.
+// It prefers the installation token of the GitHub App, and falls back to GITHUB_TOKEN of the request.
+func (h *Handler) githubToken(ctx context.Context, owner, repo string, req *requestBody) string {
+	if h.githubTokens == nil {
+		return req.GitHubToken
+	}
+	token, err := h.githubTokens.Token(ctx, owner, repo)
+	if err != nil {
+		h.log().WarnContext(ctx, "failed to get the token of the GitHub App, fall back to GITHUB_TOKEN", slog.String("error", err.Error()))
+		return req.GitHubToken
+	}
+	return token
 }
 
 func (h *Handler) assumeRole(ctx context.Context, nextIDFormat bool, idToken *github.ActionsIDToken, req *requestBody) (*responseBody, error) {
-	repo, err := h.getRepo(ctx, nextIDFormat, idToken, req)
+	input, err := h.assumeRoleInput(ctx, nextIDFormat, idToken, req)
 	if err != nil {
 		return nil, err
 	}
-	user, err := h.getUser(ctx, nextIDFormat, idToken, req)
+	return h.assumeRoleWithInput(ctx, idToken, input)
+}
+
+// assumeRoleWithInput probes the trust policy and assumes the role.
+func (h *Handler) assumeRoleWithInput(ctx context.Context, idToken *github.ActionsIDToken, input *sts.AssumeRoleInput) (*responseBody, error) {
+	if err := h.probeTrustPolicy(ctx, input); err != nil {
+		return nil, err
+	}
+
+	// assume role with the correct external ID
+	resp, err := h.sts.AssumeRole(ctx, input)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "AccessDenied" {
+			msg := fmt.Sprintf(
+				"AWS denied your access: %s, please check your trust policy accepts %q as sts:ExternalId.",
+				ae.ErrorMessage(),
+				aws.ToString(input.ExternalId),
+			)
+			return nil, &validationError{
+				message: msg,
+				code:    ae.ErrorCode(),
+			}
+		}
+		return nil, err
+	}
+	refreshToken, err := h.issueRefreshToken(idToken, input)
+	if err != nil {
+		return nil, err
+	}
+	return &responseBody{
+		AccessKeyId:     aws.ToString(resp.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(resp.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(resp.Credentials.SessionToken),
+		Expiration:      aws.ToTime(resp.Credentials.Expiration),
+		RefreshToken:    refreshToken,
+	}, nil
+}
+
+// assumeRoleInput builds the input of sts:AssumeRole with the correct external ID and session tags.
+func (h *Handler) assumeRoleInput(ctx context.Context, nextIDFormat bool, idToken *github.ActionsIDToken, req *requestBody) (*sts.AssumeRoleInput, error) {
+	repo, user, err := h.lookupRepoAndUser(ctx, nextIDFormat, idToken, req)
 	if err != nil {
 		return nil, err
 	}
@@ -484,60 +1301,34 @@ func (h *Handler) assumeRole(ctx context.Context, nextIDFormat bool, idToken *gi
 		}
 	}
 
-	// validate IAM Role
-	// https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_create_for-user_externalid.html#external-id-use
-	// > In addition when a customer gives you a role ARN, test whether you can assume the role both with and without the correct external ID.
-	validationInput := &sts.AssumeRoleInput{
+	name := req.Repository
+	if idToken != nil {
+		name = idToken.Repository
+	}
+	return &sts.AssumeRoleInput{
 		RoleArn:         aws.String(req.RoleToAssume),
 		RoleSessionName: aws.String(req.RoleSessionName),
 		Tags:            tags,
+		ExternalId:      aws.String(trustpolicy.ExternalID(name, repo.NodeID, req.UseNodeID)),
+		DurationSeconds: aws.Int32(req.DurationSeconds),
+	}, nil
+}
 
-		// set shortest duration seconds. because we don't use this credential actually.
-		DurationSeconds: aws.Int32(900),
-
-		// request without the correct external ID
-	}
-	_, err = h.sts.AssumeRole(ctx, validationInput)
-	if err == nil {
-		return nil, &validationError{
-			message: "The AssumeRolePolicy of your IAM Role is too open. Please configure ExternalId conditions.",
-		}
-	}
-	var ae smithy.APIError
-	if !errors.As(err, &ae) || ae.ErrorCode() != "AccessDenied" {
-		// We expected AccessDenied error, but got another error. (maybe network error etc.)
-		// We can't continue this process.
-		return nil, err
-	}
+// assumeRoleInputResult is the result of assumeRoleInputAsync.
+type assumeRoleInputResult struct {
+	input *sts.AssumeRoleInput
+	err   error
+}
 
-	// assume role with the correct external ID
-	input := *validationInput
-	if req.UseNodeID {
-		input.ExternalId = aws.String(repo.NodeID)
-	} else {
-		input.ExternalId = aws.String(req.Repository)
-	}
-	input.DurationSeconds = aws.Int32(req.DurationSeconds)
-	resp, err := h.sts.AssumeRole(ctx, &input)
-	if err != nil {
-		var ae smithy.APIError
-		if errors.As(err, &ae) && ae.ErrorCode() == "AccessDenied" {
-			msg := fmt.Sprintf(
-				"AWS denied your access: %s, please check your trust policy accepts %q as sts:ExternalId.",
-				ae.ErrorMessage(),
-				aws.ToString(input.ExternalId),
-			)
-			return nil, &validationError{
-				message: msg,
-			}
-		}
-		return nil, err
-	}
-	return &responseBody{
-		AccessKeyId:     aws.ToString(resp.Credentials.AccessKeyId),
-		SecretAccessKey: aws.ToString(resp.Credentials.SecretAccessKey),
-		SessionToken:    aws.ToString(resp.Credentials.SessionToken),
-	}, nil
+// assumeRoleInputAsync builds the input of sts:AssumeRole in the background.
+// The channel receives exactly one result, so the caller may leave it without receiving.
+func (h *Handler) assumeRoleInputAsync(ctx context.Context, nextIDFormat bool, idToken *github.ActionsIDToken, req *requestBody) <-chan assumeRoleInputResult {
+	ch := make(chan assumeRoleInputResult, 1)
+	go func() {
+		input, err := h.assumeRoleInput(ctx, nextIDFormat, idToken, req)
+		ch <- assumeRoleInputResult{input: input, err: err}
+	}()
+	return ch
 }
 
 // https://docs.aws.amazon.com/STS/latest/APIReference/API_Tag.html
@@ -0,0 +1,126 @@
+package assumerole
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// capabilitiesResponseBody advertises what the credential provider supports,
+// so that clients can fall back to the features of older providers.
+type capabilitiesResponseBody struct {
+	// APIVersions are the versions of the API, e.g. ["v1", "v2"].
+	APIVersions []string `json:"api_versions"`
+
+	// CredentialTypes are the types of credentials that authenticate requests: oidc and github_token.
+	CredentialTypes []string `json:"credential_types"`
+
+	// IDFormats are the formats of node IDs for use_node_id: next and legacy.
+	IDFormats []string `json:"id_formats"`
+
+	// SessionTags are the keys of the session tags with role_session_tagging.
+	SessionTags []string `json:"session_tags"`
+
+	// OutputFormats are the values of output_format other than the default.
+	OutputFormats []string `json:"output_formats"`
+
+	// MaxDurationSeconds is the maximum duration_seconds that the policies allow.
+	MaxDurationSeconds int32 `json:"max_duration_seconds"`
+
+	// Policies reports whether the policies restrict which repositories can assume which roles.
+	Policies bool `json:"policies"`
+
+	// DeploymentGates reports whether some roles require approved deployments.
+	DeploymentGates bool `json:"deployment_gates"`
+
+	// Features are the optional features, e.g. "refresh" and "dry_run".
+	Features []string `json:"features"`
+
+	// MaxRequestBodySize is the limit of the size of request bodies in bytes.
+	MaxRequestBodySize int64 `json:"max_request_body_size"`
+
+	// StrictDecoding reports whether v1 endpoints reject unknown fields.
+	StrictDecoding bool `json:"strict_decoding"`
+}
+
+// sessionTagKeys are the keys of the session tags that assumeRoleInput may set.
+var sessionTagKeys = []string{
+	"Audience",
+	"Subject",
+	"GitHub",
+	"Repository",
+	"Workflow",
+	"RunId",
+	"Actor",
+	"Commit",
+	"Branch",
+	"Environment",
+}
+
+// serveCapabilities advertises the capabilities of the credential provider.
+func (h *Handler) serveCapabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		h.handleError(w, r, &validationError{
+			message: "method " + r.Method + " is not allowed, use GET",
+			code:    "MethodNotAllowed",
+			status:  http.StatusMethodNotAllowed,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.capabilities())
+}
+
+func (h *Handler) capabilities() *capabilitiesResponseBody {
+	features := []string{"dry_run", "probe", "trust_policy", "lint_trust_policy", "oidc_identity"}
+	if len(h.refreshSecret) > 0 {
+		features = append(features, "refresh")
+	}
+	if h.iam != nil {
+		features = append(features, "lint_trust_policy_role_arn")
+	}
+	if len(h.rateLimits) > 0 {
+		features = append(features, "rate_limits")
+	}
+
+	credentialTypes := []string{"oidc"}
+	if h.githubTokenAllowed() {
+		credentialTypes = append(credentialTypes, "github_token")
+	}
+
+	maxRequestBodySize := h.maxRequestBodySize
+	if maxRequestBodySize <= 0 {
+		maxRequestBodySize = defaultMaxRequestBodySize
+	}
+
+	return &capabilitiesResponseBody{
+		APIVersions:        []string{apiVersionV1, apiVersionV2},
+		CredentialTypes:    credentialTypes,
+		IDFormats:          []string{"next", "legacy"},
+		SessionTags:        sessionTagKeys,
+		OutputFormats:      []string{outputFormatCredentialProcess},
+		MaxDurationSeconds: h.maxDurationSeconds(),
+		Policies:           len(h.policies) > 0,
+		DeploymentGates:    len(h.deploymentGates) > 0,
+		Features:           features,
+		MaxRequestBodySize: maxRequestBodySize,
+		StrictDecoding:     h.strictDecoding,
+	}
+}
+
+// maxDurationSeconds returns the maximum duration that any policy allows.
+func (h *Handler) maxDurationSeconds() int32 {
+	if len(h.policies) == 0 {
+		return maxDurationSeconds
+	}
+	var ret int32
+	for _, p := range h.policies {
+		d := p.MaxDurationSeconds
+		if d == 0 || d > maxDurationSeconds {
+			d = maxDurationSeconds
+		}
+		ret = max(ret, d)
+	}
+	return ret
+}
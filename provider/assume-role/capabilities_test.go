@@ -0,0 +1,101 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+func TestCapabilities(t *testing.T) {
+	h := &Handler{
+		refreshSecret: []byte("secret"),
+		policies: []Policy{
+			{Repositories: []string{"fuller-inc/*"}, Roles: []string{"*"}, MaxDurationSeconds: 900},
+			{Repositories: []string{"fuller-inc/deploy"}, Roles: []string{"*"}, MaxDurationSeconds: 1800},
+		},
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/capabilities", nil)
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	var resp capabilitiesResponseBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(resp.APIVersions, "v2") {
+		t.Errorf("unexpected api versions: %q", resp.APIVersions)
+	}
+	if resp.MaxDurationSeconds != 1800 {
+		t.Errorf("unexpected max duration: %d", resp.MaxDurationSeconds)
+	}
+	if !resp.Policies || resp.DeploymentGates {
+		t.Errorf("unexpected policies: %s", w.Body.String())
+	}
+	if !slices.Contains(resp.Features, "refresh") || slices.Contains(resp.Features, "lint_trust_policy_role_arn") {
+		t.Errorf("unexpected features: %q", resp.Features)
+	}
+	if resp.MaxRequestBodySize != defaultMaxRequestBodySize {
+		t.Errorf("unexpected max request body size: %d", resp.MaxRequestBodySize)
+	}
+}
+
+func TestCapabilities_Default(t *testing.T) {
+	h := &Handler{}
+	got := h.capabilities()
+	if got.MaxDurationSeconds != 3600 {
+		t.Errorf("unexpected max duration: %d", got.MaxDurationSeconds)
+	}
+	if got.Policies || slices.Contains(got.Features, "refresh") {
+		t.Errorf("unexpected capabilities: %#v", got)
+	}
+}
+
+func TestCapabilities_MethodNotAllowed(t *testing.T) {
+	h := &Handler{}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/capabilities", nil)
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("unexpected status code: %d", w.Code)
+	}
+}
+
+func TestCapabilities_SessionTags(t *testing.T) {
+	h := &Handler{
+		github: &githubClientMock{
+			GetRepoFunc: func(ctx context.Context, nextIDFormat bool, token, owner, repo string) (*github.GetRepoResponse, error) {
+				return &github.GetRepoResponse{NodeID: "R_kgDOFMsDjw"}, nil
+			},
+			GetUserFunc: func(ctx context.Context, nextIDFormat bool, token, user string) (*github.GetUserResponse, error) {
+				return &github.GetUserResponse{NodeID: "U_kgDOAAAAAA"}, nil
+			},
+		},
+	}
+	idToken := dummyIDToken()
+	idToken.Environment = "production"
+	input, err := h.assumeRoleInput(context.Background(), true, idToken, &requestBody{
+		RoleToAssume:       "arn:aws:iam::123456789012:role/assume-role-test",
+		RoleSessionName:    "GitHubActions",
+		Repository:         "fuller-inc/actions-aws-assume-role",
+		RoleSessionTagging: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// all tags are advertised.
+	for _, tag := range input.Tags {
+		if !slices.Contains(sessionTagKeys, aws.ToString(tag.Key)) {
+			t.Errorf("%s is not advertised", aws.ToString(tag.Key))
+		}
+	}
+}
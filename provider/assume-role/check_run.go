@@ -0,0 +1,189 @@
+package assumerole
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/trustpolicy"
+)
+
+const (
+	// the default name of check runs.
+	defaultCheckRunName = "aws-assume-role"
+
+	// the maximum length of the summary of check runs.
+	maxCheckRunSummaryLength = 65535
+)
+
+// CheckRunConfig is configure for reporting failures as check runs.
+// Creating check runs requires an installation token of a GitHub App,
+// so it works only with the GitHubTokenSource of the handler, e.g. github.AppTokenSource.
+// The app needs `checks: write` permission.
+type CheckRunConfig struct {
+	// Name is the name of check runs.
+	// If it is empty, "aws-assume-role" is used.
+	Name string
+}
+
+// reportCheckRun creates a check run with the details of the failure,
+// so that users can fix their trust policies.
+// Failures of creating check runs are only logged.
+func (h *Handler) reportCheckRun(ctx context.Context, idToken *github.ActionsIDToken, req *requestBody, issueErr error) {
+	cfg := h.checkRun
+	if cfg == nil || h.githubTokens == nil {
+		return
+	}
+
+	repository, sha := req.Repository, req.SHA
+	if idToken != nil {
+		repository, sha = idToken.Repository, idToken.SHA
+	}
+	owner, repo, err := splitOwnerRepo(repository)
+	if err != nil {
+		return
+	}
+	// GITHUB_TOKEN can't create check runs, so it doesn't fall back to it.
+	token, err := h.githubTokens.Token(ctx, owner, repo)
+	if err != nil {
+		h.log().WarnContext(ctx, "failed to get the token for check runs", slog.String("error", err.Error()))
+		return
+	}
+
+	name := cfg.Name
+	if name == "" {
+		name = defaultCheckRunName
+	}
+	_, err = h.github.CreateCheckRun(ctx, token, owner, repo, &github.CreateCheckRunRequest{
+		Name:        name,
+		HeadSHA:     sha,
+		Status:      github.CheckRunStatusCompleted,
+		Conclusion:  github.CheckRunConclusionFailure,
+		CompletedAt: h.now(),
+		Output: &github.CreateCheckRunOutput{
+			Title:   fmt.Sprintf("Failed to assume %s", req.RoleToAssume),
+			Summary: h.checkRunSummary(ctx, idToken, req, issueErr),
+		},
+	})
+	if err != nil {
+		h.log().WarnContext(ctx, "failed to create the check run", slog.String("error", err.Error()))
+	}
+}
+
+// checkRunSummary renders the details of the failure in markdown.
+// It never contains tokens and credentials.
+func (h *Handler) checkRunSummary(ctx context.Context, idToken *github.ActionsIDToken, req *requestBody, issueErr error) string {
+	var buf strings.Builder
+
+	buf.WriteString("## What failed\n\n")
+	buf.WriteString(markdownEscape(publicErrorMessage(issueErr)))
+	buf.WriteString("\n\n")
+
+	buf.WriteString("## Claims\n\n")
+	buf.WriteString("| Claim | Value |\n")
+	buf.WriteString("| --- | --- |\n")
+	for _, c := range observedClaims(idToken, req) {
+		if c[1] == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "| %s | <code>%s</code> |\n", c[0], markdownEscape(c[1]))
+	}
+	buf.WriteString("\n")
+
+	externalID := h.expectedExternalID(ctx, idToken, req)
+	buf.WriteString("## Expected ExternalId\n\n")
+	fmt.Fprintf(&buf, "The credential provider assumes the role with <code>%s</code> as `sts:ExternalId`.\n\n", markdownEscape(externalID))
+
+	buf.WriteString("## Suggested trust policy\n\n")
+	buf.WriteString("```json\n")
+	principal, err := h.providerPrincipal(ctx)
+	if err != nil {
+		principal = "arn:aws:iam::<the account of the credential provider>:root"
+	}
+	doc, err := trustpolicy.Generate(&trustpolicy.Options{
+		Principal:  principal,
+		ExternalID: externalID,
+		Tagging:    req.RoleSessionTagging,
+	})
+	if err == nil {
+		data, err := doc.Marshal()
+		if err == nil {
+			buf.Write(data)
+		}
+	}
+	buf.WriteString("\n```\n")
+
+	s := buf.String()
+	if len(s) > maxCheckRunSummaryLength {
+		s = strings.ToValidUTF8(s[:maxCheckRunSummaryLength], "")
+	}
+	return s
+}
+
+// publicErrorMessage returns the message that can be shown to users.
+func publicErrorMessage(err error) string {
+	var validation *validationError
+	if errors.As(err, &validation) {
+		return validation.message
+	}
+	var veto *VetoError
+	if errors.As(err, &veto) {
+		return veto.Message
+	}
+	return "Internal Server Error"
+}
+
+func observedClaims(idToken *github.ActionsIDToken, req *requestBody) [][2]string {
+	if idToken == nil {
+		return [][2]string{
+			{"repository", req.Repository},
+			{"sha", req.SHA},
+			{"ref", req.Branch},
+			{"workflow", req.Workflow},
+			{"run_id", req.RunID},
+			{"actor", req.Actor},
+		}
+	}
+	var sub string
+	if idToken.Claims != nil {
+		sub = idToken.Subject
+	}
+	return [][2]string{
+		{"sub", sub},
+		{"repository", idToken.Repository},
+		{"repository_owner", idToken.RepositoryOwner},
+		{"ref", idToken.Ref},
+		{"sha", idToken.SHA},
+		{"environment", idToken.Environment},
+		{"workflow", idToken.Workflow},
+		{"job_workflow_ref", idToken.JobWorkflowRef},
+		{"event_name", idToken.EventName},
+		{"run_id", idToken.RunID},
+		{"run_attempt", idToken.RunAttempt},
+		{"actor", idToken.Actor},
+	}
+}
+
+// expectedExternalID returns the ExternalId that the credential provider uses for the request.
+func (h *Handler) expectedExternalID(ctx context.Context, idToken *github.ActionsIDToken, req *requestBody) string {
+	if !req.UseNodeID {
+		return trustpolicy.ExternalID(req.Repository, "", false)
+	}
+	repo, err := h.getRepo(ctx, true, idToken, req)
+	if err != nil {
+		return "(the node ID of " + req.Repository + ")"
+	}
+	return trustpolicy.ExternalID(req.Repository, repo.NodeID, true)
+}
+
+// markdownEscape escapes s for HTML elements in markdown tables.
+func markdownEscape(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, "|", "&#124;")
+	s = strings.ReplaceAll(s, "\n", "<br>")
+	return s
+}
@@ -0,0 +1,141 @@
+package assumerole
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+type staticTokenSource string
+
+func (s staticTokenSource) Token(ctx context.Context, owner, repo string) (string, error) {
+	return string(s), nil
+}
+
+// githubClientCheckRunRecorder records the check runs.
+type githubClientCheckRunRecorder struct {
+	githubClientDummy
+	tokens []string
+	runs   []*github.CreateCheckRunRequest
+}
+
+func (c *githubClientCheckRunRecorder) CreateCheckRun(ctx context.Context, token, owner, repo string, run *github.CreateCheckRunRequest) (*github.CreateCheckRunResponse, error) {
+	c.tokens = append(c.tokens, token)
+	c.runs = append(c.runs, run)
+	return c.githubClientDummy.CreateCheckRun(ctx, token, owner, repo, run)
+}
+
+func TestCheckRun(t *testing.T) {
+	client := &githubClientCheckRunRecorder{}
+	h, err := NewHandlerWithOptions(
+		WithGitHubClient(client),
+		WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+			return &stsClientMock{
+				AssumeRoleFunc: func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+					return nil, errAccessDenied
+				},
+				GetCallerIdentityFunc: func(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+					return &sts.GetCallerIdentityOutput{
+						Account: aws.String("053160724612"),
+					}, nil
+				},
+			}, nil
+		}),
+		WithGitHubTokenSource(staticTokenSource("ghs_installationToken")),
+		WithCheckRun(&CheckRunConfig{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(dummyAssumeRoleRequest))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	if len(client.runs) != 1 {
+		t.Fatalf("want 1 check run, got %d", len(client.runs))
+	}
+	if client.tokens[0] != "ghs_installationToken" {
+		t.Errorf("unexpected token: %q", client.tokens[0])
+	}
+	run := client.runs[0]
+	if run.Name != "aws-assume-role" {
+		t.Errorf("unexpected name: %q", run.Name)
+	}
+	if run.HeadSHA != "e3a45c6c16c1464826b36a598ff39e6cc98c4da4" {
+		t.Errorf("unexpected head sha: %q", run.HeadSHA)
+	}
+	if run.Conclusion != github.CheckRunConclusionFailure {
+		t.Errorf("unexpected conclusion: %q", run.Conclusion)
+	}
+
+	summary := run.Output.Summary
+	for _, want := range []string{
+		"AWS denied your access",
+		"<code>repo:fuller-inc/actions-aws-assume-role:ref:refs/heads/main</code>",
+		"<code>fuller-inc/actions-aws-assume-role</code> as `sts:ExternalId`",
+		`"AWS": "arn:aws:iam::053160724612:root"`,
+		`"sts:ExternalId": "fuller-inc/actions-aws-assume-role"`,
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("the summary doesn't contain %q:\n%s", want, summary)
+		}
+	}
+}
+
+func TestCheckRun_Disabled(t *testing.T) {
+	h := &Handler{
+		// CreateCheckRun panics if it is called.
+		github: &githubClientMock{},
+	}
+	h.reportCheckRun(context.Background(), nil, &requestBody{}, errors.New("error"))
+
+	// check runs require the token of the GitHub App.
+	h.checkRun = &CheckRunConfig{}
+	h.reportCheckRun(context.Background(), nil, &requestBody{
+		Repository:  "fuller-inc/actions-aws-assume-role",
+		GitHubToken: "ghs_dummyGitHubToken",
+	}, errors.New("error"))
+}
+
+func TestCheckRunSummary_InternalError(t *testing.T) {
+	h := &Handler{
+		github: &githubClientDummy{},
+		sts: &stsClientMock{
+			GetCallerIdentityFunc: func(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+				return nil, errors.New("network error")
+			},
+		},
+	}
+	req := &requestBody{
+		Repository:         "fuller-inc/actions-aws-assume-role",
+		UseNodeID:          true,
+		RoleSessionTagging: true,
+		Workflow:           "a | b",
+	}
+	summary := h.checkRunSummary(context.Background(), nil, req, errors.New("secret internal details"))
+	if strings.Contains(summary, "secret internal details") {
+		t.Errorf("the summary leaks the internal error:\n%s", summary)
+	}
+	for _, want := range []string{
+		"Internal Server Error",
+		"<code>a &#124; b</code>",
+		`"sts:ExternalId": "R_kgDOFMsDjw"`,
+		`"Action": "sts:TagSession"`,
+		"arn:aws:iam::<the account of the credential provider>:root",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("the summary doesn't contain %q:\n%s", want, summary)
+		}
+	}
+}
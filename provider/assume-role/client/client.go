@@ -0,0 +1,465 @@
+// Package client is a client for the credential provider of actions-aws-assume-role.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultEndpoint is the endpoint of the credential provider that @fuller-inc hosts.
+	DefaultEndpoint = "https://uw4qs7ndjj.execute-api.us-east-1.amazonaws.com/assume-role"
+
+	// DefaultMaxRetries is the default number of retries.
+	DefaultMaxRetries = 3
+
+	// The value of User-Agent header
+	userAgent = "actions-aws-assume-role"
+)
+
+// the base delay of the exponential backoff.
+var retryBaseDelay = 500 * time.Millisecond
+
+// Request is a request for the /assume-role endpoint.
+type Request struct {
+	GitHubToken        string `json:"github_token"`
+	IDToken            string `json:"id_token,omitempty"`
+	RoleToAssume       string `json:"role_to_assume"`
+	RoleSessionName    string `json:"role_session_name"`
+	DurationSeconds    int32  `json:"duration_seconds"`
+	APIURL             string `json:"api_url"`
+	Repository         string `json:"repository"`
+	UseNodeID          bool   `json:"use_node_id"`
+	SHA                string `json:"sha"`
+	RoleSessionTagging bool   `json:"role_session_tagging"`
+	RunID              string `json:"run_id"`
+	Workflow           string `json:"workflow"`
+	Actor              string `json:"actor"`
+	Branch             string `json:"branch"`
+
+	// DryRun explains how the role would be assumed without issuing credentials.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Probe runs the negative ExternalId probe in dry run mode.
+	Probe bool `json:"probe,omitempty"`
+}
+
+// RefreshRequest is a request for the /refresh endpoint.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token,omitempty"`
+	GitHubToken  string `json:"github_token,omitempty"`
+}
+
+// TrustPolicyRequest is a request for the /trust-policy endpoint.
+type TrustPolicyRequest struct {
+	Repository         string `json:"repository"`
+	UseNodeID          bool   `json:"use_node_id"`
+	RoleSessionTagging bool   `json:"role_session_tagging"`
+	Environment        string `json:"environment,omitempty"`
+
+	// GitHubToken is used for looking up the node id of the repository
+	// if the credential provider has no GitHub App.
+	GitHubToken string `json:"github_token,omitempty"`
+}
+
+// TrustPolicyResponse is a response from the /trust-policy endpoint.
+type TrustPolicyResponse struct {
+	ExternalID string `json:"external_id"`
+	Principal  string `json:"principal"`
+
+	// Policy is the trust policy document of the IAM role.
+	Policy json.RawMessage `json:"policy"`
+}
+
+// Capabilities is what the credential provider supports.
+type Capabilities struct {
+	// APIVersions are the versions of the API, e.g. ["v1", "v2"].
+	APIVersions []string `json:"api_versions"`
+
+	// CredentialTypes are the types of credentials that authenticate requests, e.g. ["oidc", "github_token"].
+	CredentialTypes []string `json:"credential_types"`
+
+	// IDFormats are the formats of node IDs for UseNodeID, e.g. ["next", "legacy"].
+	IDFormats []string `json:"id_formats"`
+
+	// SessionTags are the keys of the session tags with RoleSessionTagging.
+	SessionTags []string `json:"session_tags"`
+
+	// OutputFormats are the values of output_format other than the default.
+	OutputFormats []string `json:"output_formats"`
+
+	// MaxDurationSeconds is the maximum DurationSeconds that the provider allows.
+	MaxDurationSeconds int32 `json:"max_duration_seconds"`
+
+	// Policies reports whether the provider restricts which repositories can assume which roles.
+	Policies bool `json:"policies"`
+
+	// DeploymentGates reports whether some roles require approved deployments.
+	DeploymentGates bool `json:"deployment_gates"`
+
+	// Features are the optional features, e.g. "refresh" and "dry_run".
+	Features []string `json:"features"`
+
+	// MaxRequestBodySize is the limit of the size of request bodies in bytes.
+	// Larger requests are rejected with 413 Request Entity Too Large.
+	MaxRequestBodySize int64 `json:"max_request_body_size"`
+
+	// StrictDecoding reports whether v1 endpoints reject unknown fields.
+	// v2 endpoints always reject them.
+	StrictDecoding bool `json:"strict_decoding"`
+}
+
+// legacyCapabilities returns the capabilities of the providers that don't have the /capabilities endpoint.
+// It returns a new value for each call, so that callers can modify it.
+func legacyCapabilities() *Capabilities {
+	return &Capabilities{
+		APIVersions:        []string{"v1"},
+		CredentialTypes:    []string{"oidc", "github_token"},
+		IDFormats:          []string{"next", "legacy"},
+		MaxDurationSeconds: 3600,
+	}
+}
+
+// HasAPIVersion reports whether the provider supports the version of the API.
+func (c *Capabilities) HasAPIVersion(version string) bool {
+	return slices.Contains(c.APIVersions, version)
+}
+
+// HasFeature reports whether the provider supports the feature.
+func (c *Capabilities) HasFeature(feature string) bool {
+	return slices.Contains(c.Features, feature)
+}
+
+// LegacyNodeIDUsage is a pair of a repository and a role that is authorized only by the legacy node IDs.
+type LegacyNodeIDUsage struct {
+	Repository   string `json:"repository"`
+	RoleARN      string `json:"role_arn"`
+	LegacyNodeID string `json:"legacy_node_id"`
+
+	// NextNodeID is empty if the provider failed to look it up.
+	NextNodeID string `json:"next_node_id,omitempty"`
+
+	// TrustPolicyDiff is the changes of the trust policy that are needed for migrating to the next format.
+	TrustPolicyDiff []*TrustPolicyChange `json:"trust_policy_diff"`
+
+	Count     int64     `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// TrustPolicyChange is a change of a condition of the trust policy.
+type TrustPolicyChange struct {
+	// ConditionKey is the key of the condition, e.g. "sts:ExternalId".
+	ConditionKey string `json:"condition_key"`
+	Legacy       string `json:"legacy"`
+	Next         string `json:"next"`
+}
+
+// Response is a response from the credential provider.
+type Response struct {
+	AccessKeyID     string    `json:"access_key_id"`
+	SecretAccessKey string    `json:"secret_access_key"`
+	SessionToken    string    `json:"session_token"`
+	Expiration      time.Time `json:"expiration,omitzero"`
+	RefreshToken    string    `json:"refresh_token,omitempty"`
+	Message         string    `json:"message,omitempty"`
+	Warning         string    `json:"warning,omitempty"`
+
+	// Explanation is available in dry run mode.
+	Explanation json.RawMessage `json:"explanation,omitempty"`
+}
+
+// CredentialProcessOutput is the output of credential_process.
+// https://docs.aws.amazon.com/sdkref/latest/guide/feature-process-credentials.html
+type CredentialProcessOutput struct {
+	Version         int        `json:"Version"`
+	AccessKeyID     string     `json:"AccessKeyId"`
+	SecretAccessKey string     `json:"SecretAccessKey"`
+	SessionToken    string     `json:"SessionToken"`
+	Expiration      *time.Time `json:"Expiration,omitempty"`
+}
+
+// CredentialProcessOutput converts the response into the output of credential_process.
+func (resp *Response) CredentialProcessOutput() *CredentialProcessOutput {
+	out := &CredentialProcessOutput{
+		Version:         1,
+		AccessKeyID:     resp.AccessKeyID,
+		SecretAccessKey: resp.SecretAccessKey,
+		SessionToken:    resp.SessionToken,
+	}
+	if !resp.Expiration.IsZero() {
+		expiration := resp.Expiration
+		out.Expiration = &expiration
+	}
+	return out
+}
+
+// Error is an error returned by the credential provider.
+type Error struct {
+	StatusCode int
+	Message    string
+
+	// RetryAfter is the value of Retry-After header.
+	RetryAfter time.Duration
+}
+
+func (err *Error) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("client: unexpected status code: %d", err.StatusCode)
+	}
+	return fmt.Sprintf("client: %s (status code: %d)", err.Message, err.StatusCode)
+}
+
+// Temporary reports whether the error is temporary and the request may succeed if retried.
+// 500 Internal Server Error is not temporary, because the credential provider may have already
+// made side effects such as commit statuses and sts:AssumeRole calls.
+func (err *Error) Temporary() bool {
+	switch err.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Client is a client for the credential provider.
+type Client struct {
+	endpoint   *url.URL
+	httpClient *http.Client
+
+	// MaxRetries is the maximum number of retries for temporary errors.
+	MaxRetries int
+}
+
+// NewClient returns a new client for the credential provider.
+// If endpoint is empty, DefaultEndpoint is used.
+// If httpClient is nil, http.DefaultClient is used.
+func NewClient(endpoint string, httpClient *http.Client) (*Client, error) {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: unknown scheme: %q", u.Scheme)
+	}
+	return &Client{
+		endpoint:   u,
+		httpClient: httpClient,
+		MaxRetries: DefaultMaxRetries,
+	}, nil
+}
+
+// AssumeRole requests a new credential.
+func (c *Client) AssumeRole(ctx context.Context, req *Request) (*Response, error) {
+	var resp Response
+	if err := c.do(ctx, c.endpoint, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Refresh refreshes the credential with the refresh token.
+func (c *Client) Refresh(ctx context.Context, req *RefreshRequest) (*Response, error) {
+	var resp Response
+	if err := c.do(ctx, c.resolve("refresh"), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// TrustPolicy generates the trust policy of IAM roles for the repository.
+func (c *Client) TrustPolicy(ctx context.Context, req *TrustPolicyRequest) (*TrustPolicyResponse, error) {
+	var resp TrustPolicyResponse
+	if err := c.do(ctx, c.resolve("trust-policy"), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Capabilities gets the capabilities of the provider.
+// Older providers don't have the /capabilities endpoint,
+// so it returns the capabilities of v1, which all providers support, if the provider rejects the request.
+func (c *Client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	data, err := c.sendWithRetry(ctx, http.MethodGet, c.resolve("capabilities"), nil, nil)
+	if err != nil {
+		var clientErr *Error
+		if errors.As(err, &clientErr) && 400 <= clientErr.StatusCode && clientErr.StatusCode < 500 && !clientErr.Temporary() {
+			return legacyCapabilities(), nil
+		}
+		return nil, err
+	}
+	var capabilities Capabilities
+	if err := json.Unmarshal(data, &capabilities); err != nil {
+		return nil, err
+	}
+	return &capabilities, nil
+}
+
+// LegacyNodeIDs lists the repositories and roles that are authorized only by the legacy node IDs.
+// It requires the admin token of the provider.
+func (c *Client) LegacyNodeIDs(ctx context.Context, adminToken string) ([]*LegacyNodeIDUsage, error) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+adminToken)
+	data, err := c.sendWithRetry(ctx, http.MethodGet, c.resolve("admin/legacy-node-ids"), header, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Usages []*LegacyNodeIDUsage `json:"usages"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Usages, nil
+}
+
+// resolve resolves the path relative to the endpoint.
+// e.g. https://example.com/assume-role -> https://example.com/refresh
+func (c *Client) resolve(path string) *url.URL {
+	return c.endpoint.ResolveReference(&url.URL{Path: path})
+}
+
+// do sends the request with retries.
+func (c *Client) do(ctx context.Context, u *url.URL, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	data, err := c.sendWithRetry(ctx, http.MethodPost, u, nil, body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// sendWithRetry sends the request until it succeeds or fails with a permanent error.
+func (c *Client) sendWithRetry(ctx context.Context, method string, u *url.URL, header http.Header, body []byte) ([]byte, error) {
+	var lastErr error
+	for i := 0; ; i++ {
+		data, err := c.send(ctx, method, u, header, body)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
+		if i >= c.MaxRetries || !isTemporary(err) {
+			return nil, lastErr
+		}
+
+		// exponential backoff with full jitter
+		delay := retryBaseDelay << i
+		delay = time.Duration(rand.Int64N(int64(delay)) + 1)
+		var clientErr *Error
+		if errors.As(err, &clientErr) && clientErr.RetryAfter > delay {
+			delay = clientErr.RetryAfter
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Join(lastErr, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, u *url.URL, header http.Header, body []byte) ([]byte, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		// the credential provider has already handled the request, so it is not retried.
+		return nil, &readError{err: err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp, data)
+	}
+	return data, nil
+}
+
+func decodeError(resp *http.Response, data []byte) error {
+	ret := &Error{
+		StatusCode: resp.StatusCode,
+	}
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		ret.RetryAfter = time.Duration(s) * time.Second
+	}
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil {
+		ret.Message = body.Message
+	}
+	return ret
+}
+
+// readError is an error while reading the response body.
+type readError struct {
+	err error
+}
+
+func (err *readError) Error() string {
+	return fmt.Sprintf("client: failed to read the response: %v", err.err)
+}
+
+func (err *readError) Unwrap() error {
+	return err.err
+}
+
+func isTemporary(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var clientErr *Error
+	if errors.As(err, &clientErr) {
+		return clientErr.Temporary()
+	}
+	var readErr *readError
+	if errors.As(err, &readErr) {
+		return false
+	}
+
+	// network errors before any response arrives.
+	return true
+}
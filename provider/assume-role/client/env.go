@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+const (
+	defaultAPIURL          = "https://api.github.com"
+	defaultRoleSessionName = "GitHubActions"
+	defaultDurationSeconds = 3600
+)
+
+// NewRequestFromEnv builds a request from the environment values of GitHub Actions.
+// If the OIDC token is available, it also requests a new OIDC token.
+// The caller must set RoleToAssume of the returned request.
+func (c *Client) NewRequestFromEnv(ctx context.Context) (*Request, error) {
+	req := &Request{
+		GitHubToken:     os.Getenv("GITHUB_TOKEN"),
+		RoleSessionName: defaultRoleSessionName,
+		DurationSeconds: defaultDurationSeconds,
+		APIURL:          os.Getenv("GITHUB_API_URL"),
+		Repository:      os.Getenv("GITHUB_REPOSITORY"),
+		SHA:             os.Getenv("GITHUB_SHA"),
+		RunID:           os.Getenv("GITHUB_RUN_ID"),
+		Workflow:        os.Getenv("GITHUB_WORKFLOW"),
+		Actor:           os.Getenv("GITHUB_ACTOR"),
+		Branch:          os.Getenv("GITHUB_REF"),
+	}
+	if req.APIURL == "" {
+		req.APIURL = defaultAPIURL
+	}
+	if req.Repository == "" || req.SHA == "" || req.RunID == "" || req.Workflow == "" || req.Actor == "" {
+		return nil, errors.New("client: missing required environment value. Are you running in GitHub Actions?")
+	}
+
+	if IsIDTokenAvailable() {
+		idToken, err := c.GetIDToken(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		req.IDToken = idToken
+	}
+	return req, nil
+}
+
+// IsIDTokenAvailable reports whether the OIDC token is available.
+// The workflow needs the `id-token: write` permission for using the OIDC token.
+func IsIDTokenAvailable() bool {
+	return os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") != "" && os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN") != ""
+}
+
+// GetIDToken requests a new OIDC token to GitHub Actions.
+// If audience is empty, the default audience is used.
+// https://docs.github.com/en/actions/deployment/security-hardening-your-deployments/about-security-hardening-with-openid-connect
+func (c *Client) GetIDToken(ctx context.Context, audience string) (string, error) {
+	tokenURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	token := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if tokenURL == "" || token == "" {
+		return "", errors.New("client: the OIDC token is not available. Did you configure the `id-token: write` permission?")
+	}
+
+	u, err := url.Parse(tokenURL)
+	if err != nil {
+		return "", err
+	}
+	if audience != "" {
+		q := u.Query()
+		q.Set("audience", audience)
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("client: failed to get the OIDC token: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.Value, nil
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewRequestFromEnv(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer dummy-request-token"; got != want {
+			t.Errorf("unexpected authorization header: want %q, got %q", want, got)
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"value":"dummyGitHubIDToken"}`))
+	}))
+	defer ts.Close()
+
+	t.Setenv("GITHUB_TOKEN", "ghs_dummyGitHubToken")
+	t.Setenv("GITHUB_API_URL", "")
+	t.Setenv("GITHUB_REPOSITORY", "fuller-inc/actions-aws-assume-role")
+	t.Setenv("GITHUB_SHA", "e3a45c6c16c1464826b36a598ff39e6cc98c4da4")
+	t.Setenv("GITHUB_RUN_ID", "1234567890")
+	t.Setenv("GITHUB_WORKFLOW", "test")
+	t.Setenv("GITHUB_ACTOR", "shogo82148")
+	t.Setenv("GITHUB_REF", "refs/heads/main")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", ts.URL+"/token?api-version=2.0")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "dummy-request-token")
+
+	c, err := NewClient("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := c.NewRequestFromEnv(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.IDToken != "dummyGitHubIDToken" {
+		t.Errorf("unexpected id token: %q", req.IDToken)
+	}
+	if req.APIURL != "https://api.github.com" {
+		t.Errorf("unexpected api url: %q", req.APIURL)
+	}
+	if req.Repository != "fuller-inc/actions-aws-assume-role" {
+		t.Errorf("unexpected repository: %q", req.Repository)
+	}
+	if req.RoleSessionName != "GitHubActions" {
+		t.Errorf("unexpected role session name: %q", req.RoleSessionName)
+	}
+	if req.DurationSeconds != 3600 {
+		t.Errorf("unexpected duration: %d", req.DurationSeconds)
+	}
+}
+
+func TestNewRequestFromEnv_NotInGitHubActions(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "")
+
+	c, err := NewClient("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.NewRequestFromEnv(context.Background()); err == nil {
+		t.Error("want error, but not")
+	}
+}
+
+func TestGetIDToken_Audience(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("audience"), "sts.amazonaws.com"; got != want {
+			t.Errorf("unexpected audience: want %q, got %q", want, got)
+		}
+		if got, want := r.URL.Query().Get("api-version"), "2.0"; got != want {
+			t.Errorf("unexpected api-version: want %q, got %q", want, got)
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"value":"dummyGitHubIDToken"}`))
+	}))
+	defer ts.Close()
+
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", ts.URL+"/token?api-version=2.0")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "dummy-request-token")
+
+	c, err := NewClient("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := c.GetIDToken(context.Background(), "sts.amazonaws.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "dummyGitHubIDToken" {
+		t.Errorf("unexpected id token: %q", token)
+	}
+}
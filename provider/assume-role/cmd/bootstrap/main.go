@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	assumerole "github.com/fuller-inc/actions-aws-assume-role/provider/assume-role"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/metrics"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/tracing"
 	"github.com/shogo82148/aws-xray-yasdk-go/xray/xrayslog"
 	"github.com/shogo82148/ridgenative"
 )
@@ -23,7 +28,69 @@ func init() {
 }
 
 func main() {
-	h := assumerole.NewHandler()
-	http.Handle("/", h)
+	tracer, err := tracing.NewFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	// metrics are written in the CloudWatch Embedded Metric Format if the namespace is configured.
+	namespace := os.Getenv("METRICS_NAMESPACE")
+	var registry *metrics.Registry
+	if namespace != "" {
+		registry = metrics.NewRegistry()
+		registry.EnableEMF()
+	}
+
+	// the GitHub App is used for GitHub API calls made by the provider itself if it is configured.
+	var tokens assumerole.GitHubTokenSource
+	var checkRun *assumerole.CheckRunConfig
+	if appID := os.Getenv("GITHUB_APP_ID"); appID != "" {
+		tokens, err = github.NewAppTokenSource(&github.AppConfig{
+			AppID:      appID,
+			PrivateKey: []byte(os.Getenv("GITHUB_APP_PRIVATE_KEY")),
+			HTTPClient: tracer.HTTPClient(nil),
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		// check runs require the GitHub App.
+		if name := os.Getenv("CHECK_RUN_NAME"); name != "" {
+			checkRun = &assumerole.CheckRunConfig{
+				Name: name,
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	h, err := assumerole.NewHandlerWithConfig(ctx, &assumerole.Config{
+		RefreshSecret:     []byte(os.Getenv("REFRESH_TOKEN_SECRET")),
+		Metrics:           registry,
+		Tracer:            tracer,
+		GitHubTokenSource: tokens,
+		CheckRun:          checkRun,
+	})
+	cancel()
+	if err != nil {
+		panic(err)
+	}
+	http.Handle("/", withFlush(h, registry, namespace, tracer))
 	ridgenative.ListenAndServe(":8080", nil)
 }
+
+// withFlush writes the metrics and exports the spans after each invocation,
+// because the execution environment may be frozen until the next invocation.
+// CloudWatch Logs extracts the metrics from the logs in stdout.
+func withFlush(h http.Handler, registry *metrics.Registry, namespace string, tracer tracing.Tracer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		if registry != nil {
+			if err := registry.WriteEMF(os.Stdout, namespace, time.Now()); err != nil {
+				slog.WarnContext(r.Context(), "failed to write metrics", slog.String("error", err.Error()))
+			}
+		}
+		if err := tracing.Flush(r.Context(), tracer); err != nil {
+			slog.WarnContext(r.Context(), "failed to export spans", slog.String("error", err.Error()))
+		}
+	})
+}
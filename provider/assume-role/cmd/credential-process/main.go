@@ -0,0 +1,72 @@
+// The command credential-process is a helper for credential_process of AWS SDKs.
+// It fetches credentials from the credential provider in the same way as the action does.
+//
+// Configure it in your AWS config file (~/.aws/config):
+//
+//	[profile github-actions]
+//	credential_process = credential-process -role-to-assume arn:aws:iam::123456789012:role/assume-role-test
+//
+// It works only on GitHub Actions, because it requires the environment values of GitHub Actions.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/client"
+)
+
+func main() {
+	var endpoint, roleToAssume, roleSessionName string
+	var durationSeconds int
+	var roleSessionTagging, useNodeID bool
+	flag.StringVar(&endpoint, "provider-endpoint", client.DefaultEndpoint, "URL for credential provider")
+	flag.StringVar(&roleToAssume, "role-to-assume", "", "the ARN of the role to assume")
+	flag.StringVar(&roleSessionName, "role-session-name", "GitHubActions", "role session name")
+	flag.IntVar(&durationSeconds, "role-duration-seconds", 3600, "role duration in seconds")
+	flag.BoolVar(&roleSessionTagging, "role-session-tagging", false, "enable session tagging")
+	flag.BoolVar(&useNodeID, "use-node-id", false, "use the node id of the repository instead of its name")
+	flag.Parse()
+
+	if roleToAssume == "" {
+		fmt.Fprintln(os.Stderr, "missing required flag: -role-to-assume")
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c, err := client.NewClient(endpoint, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	req, err := c.NewRequestFromEnv(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	req.RoleToAssume = roleToAssume
+	req.RoleSessionName = roleSessionName
+	req.DurationSeconds = int32(durationSeconds)
+	req.RoleSessionTagging = roleSessionTagging
+	req.UseNodeID = useNodeID
+
+	resp, err := c.AssumeRole(ctx, req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if resp.Warning != "" {
+		// stdout is reserved for the credential, so write warnings into stderr.
+		fmt.Fprint(os.Stderr, resp.Warning)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(resp.CredentialProcessOutput()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,80 @@
+// The command legacy-node-ids lists the repositories and roles that are authorized only by the legacy node IDs,
+// and the changes of their trust policies that are needed for migrating to the next format.
+//
+//	ADMIN_TOKEN=... legacy-node-ids -provider-endpoint https://assume-role.example.com/
+//
+// The admin token of the provider is read from the ADMIN_TOKEN environment value.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/client"
+)
+
+func main() {
+	var endpoint string
+	var jsonOutput bool
+	// the admin endpoint is not available on the hosted provider on AWS Lambda, so there is no default.
+	flag.StringVar(&endpoint, "provider-endpoint", "", "URL for credential provider. e.g. https://assume-role.example.com/")
+	flag.BoolVar(&jsonOutput, "json", false, "output in JSON")
+	flag.Parse()
+
+	if endpoint == "" {
+		fmt.Fprintln(os.Stderr, "missing required flag: -provider-endpoint")
+		os.Exit(2)
+	}
+
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "missing required environment value: ADMIN_TOKEN")
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c, err := client.NewClient(endpoint, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	usages, err := c.LegacyNodeIDs(ctx, token)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(usages); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	for i, u := range usages {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s %s (%d requests, last seen %s)\n", u.Repository, u.RoleARN, u.Count, u.LastSeen.Format(time.RFC3339))
+		fmt.Printf("  legacy node id: %s\n", u.LegacyNodeID)
+		if u.NextNodeID == "" {
+			fmt.Println("  next node id:   unknown")
+			continue
+		}
+		fmt.Printf("  next node id:   %s\n", u.NextNodeID)
+		for _, change := range u.TrustPolicyDiff {
+			fmt.Printf("  - %s: %s\n", strconv.Quote(change.ConditionKey), strconv.Quote(change.Legacy))
+			fmt.Printf("  + %s: %s\n", strconv.Quote(change.ConditionKey), strconv.Quote(change.Next))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// The command lint-trust-policy analyzes the trust policy of IAM roles for the credential provider.
+//
+//	lint-trust-policy -file policy.json -principal arn:aws:iam::053160724612:root -external-id R_kgDOFMsDjw
+//	lint-trust-policy -role-arn arn:aws:iam::123456789012:role/assume-role-test -role-session-tagging
+//
+// With -role-arn, it fetches the trust policy with iam:GetRole using your AWS credentials.
+// It exits with status 1 if it finds any errors.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/trustpolicy"
+)
+
+func main() {
+	var file, roleARN, principal, externalID string
+	var roleSessionTagging bool
+	flag.StringVar(&file, "file", "", "path to the trust policy document. '-' reads it from stdin")
+	flag.StringVar(&roleARN, "role-arn", "", "the ARN of the role whose trust policy is analyzed")
+	flag.StringVar(&principal, "principal", "", "the principal of the credential provider. e.g. arn:aws:iam::053160724612:root")
+	flag.StringVar(&externalID, "external-id", "", "the ExternalId that the credential provider uses for the repository")
+	flag.BoolVar(&roleSessionTagging, "role-session-tagging", false, "session tagging is enabled")
+	flag.Parse()
+
+	if (file == "") == (roleARN == "") {
+		fmt.Fprintln(os.Stderr, "either -file or -role-arn is required")
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	doc, err := load(ctx, file, roleARN)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	issues := trustpolicy.Lint(doc, &trustpolicy.LintOptions{
+		Principal:  principal,
+		ExternalID: externalID,
+		Tagging:    roleSessionTagging,
+	})
+	var failed bool
+	for _, issue := range issues {
+		if issue.Severity == trustpolicy.SeverityError {
+			failed = true
+		}
+		if issue.Statement < 0 {
+			fmt.Printf("%s: %s: %s\n", issue.Severity, issue.Code, issue.Message)
+		} else {
+			fmt.Printf("%s: %s: Statement[%d]: %s\n", issue.Severity, issue.Code, issue.Statement, issue.Message)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func load(ctx context.Context, file, roleARN string) (*trustpolicy.Document, error) {
+	if roleARN != "" {
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load SDK config: %w", err)
+		}
+		return trustpolicy.FetchRolePolicy(ctx, iam.NewFromConfig(cfg), roleARN)
+	}
+
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return trustpolicy.Parse(data)
+}
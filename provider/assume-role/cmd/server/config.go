@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	assumerole "github.com/fuller-inc/actions-aws-assume-role/provider/assume-role"
+)
+
+// config is the configuration of the server.
+type config struct {
+	// Listen is the TCP address to listen on.
+	Listen string `json:"listen"`
+
+	// TLS enables HTTPS if it is configured.
+	TLS *tlsConfig `json:"tls,omitempty"`
+
+	ReadTimeout       duration `json:"read_timeout"`
+	ReadHeaderTimeout duration `json:"read_header_timeout"`
+	WriteTimeout      duration `json:"write_timeout"`
+	IdleTimeout       duration `json:"idle_timeout"`
+
+	// ShutdownDelay is the time to wait before shutting down the server.
+	// The server reports that it is not ready during the delay,
+	// so that load balancers can stop sending new requests.
+	ShutdownDelay duration `json:"shutdown_delay"`
+
+	// ShutdownTimeout is the maximum time to wait for in-flight requests.
+	ShutdownTimeout duration `json:"shutdown_timeout"`
+
+	// GitHub is a list of GitHub instances that the server accepts.
+	GitHub []*githubConfig `json:"github"`
+
+	// Policies restrict which repositories can assume which roles.
+	Policies []assumerole.Policy `json:"policies,omitempty"`
+
+	// DeploymentGates require approved deployments for assuming the roles.
+	DeploymentGates []assumerole.DeploymentGate `json:"deployment_gates,omitempty"`
+
+	// ProbeStrategies detect trust policies that are too open.
+	// e.g. ["no_external_id", "random_external_id", "sibling_repository"]
+	ProbeStrategies []assumerole.ProbeStrategy `json:"probe_strategies,omitempty"`
+
+	// ProbeCacheTTL is how long the passed results of the probes are cached.
+	// Caching is disabled if it is zero.
+	ProbeCacheTTL duration `json:"probe_cache_ttl,omitempty"`
+
+	// RateLimits limit the rate of requests per repository, owner or role.
+	RateLimits []assumerole.RateLimit `json:"rate_limits,omitempty"`
+
+	// RateLimitRedis shares the token buckets of the rate limits between servers.
+	// If it is not configured, the buckets are stored in memory of each server.
+	RateLimitRedis *rateLimitRedisConfig `json:"rate_limit_redis,omitempty"`
+
+	// MaxRequestBodySize is the limit of the size of request bodies in bytes.
+	// If it is zero, 64 KiB is used.
+	MaxRequestBodySize int64 `json:"max_request_body_size,omitempty"`
+
+	// StrictDecoding rejects unknown fields in request bodies.
+	StrictDecoding bool `json:"strict_decoding,omitempty"`
+
+	// GitHubTokenAuth restricts the deprecated authentication with GITHUB_TOKEN.
+	GitHubTokenAuth *assumerole.GitHubTokenAuth `json:"github_token_auth,omitempty"`
+
+	// Webhooks are called before and after issuing credentials.
+	Webhooks []*webhookConfig `json:"webhooks,omitempty"`
+
+	// CommitStatus enables reporting each issuance as a commit status.
+	CommitStatus *commitStatusConfig `json:"commit_status,omitempty"`
+
+	// CheckRun enables reporting failures as check runs.
+	// It requires the GitHub App of each instance.
+	CheckRun *checkRunConfig `json:"check_run,omitempty"`
+
+	// TrustPolicyLinter configures the /lint-trust-policy endpoint.
+	TrustPolicyLinter *trustPolicyLinterConfig `json:"trust_policy_linter,omitempty"`
+}
+
+type tlsConfig struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+}
+
+type githubConfig struct {
+	// PathPrefix is the prefix of the endpoint for the instance. e.g. "/ghes"
+	PathPrefix string `json:"path_prefix"`
+
+	// APIURL is the URL of GitHub API. e.g. "https://ghes.example.com/api/v3"
+	APIURL string `json:"api_url"`
+
+	// OIDCIssuer is the issuer of OIDC tokens. e.g. "https://ghes.example.com/_services/token"
+	OIDCIssuer string `json:"oidc_issuer"`
+
+	// Policies restrict which repositories can assume which roles.
+	// If it is empty, the top level policies are used.
+	Policies []assumerole.Policy `json:"policies,omitempty"`
+
+	// App is the GitHub App for GitHub API calls made by the provider itself.
+	App *githubAppConfig `json:"app,omitempty"`
+}
+
+type githubAppConfig struct {
+	// AppID is the ID or the client ID of the GitHub App.
+	AppID string `json:"app_id"`
+
+	// PrivateKeyFile is the path to the PEM encoded private key of the GitHub App.
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+
+	// PrivateKeyEnv is the name of the environment value that has the PEM encoded private key.
+	PrivateKeyEnv string `json:"private_key_env,omitempty"`
+}
+
+// privateKey reads the private key of the GitHub App.
+func (app *githubAppConfig) privateKey() ([]byte, error) {
+	if app.PrivateKeyEnv != "" {
+		key := os.Getenv(app.PrivateKeyEnv)
+		if key == "" {
+			return nil, fmt.Errorf("the environment value %s is empty", app.PrivateKeyEnv)
+		}
+		return []byte(key), nil
+	}
+	return os.ReadFile(app.PrivateKeyFile)
+}
+
+type checkRunConfig struct {
+	// Name is the name of check runs.
+	Name string `json:"name,omitempty"`
+}
+
+type trustPolicyLinterConfig struct {
+	// FetchRoles allows fetching the trust policies of roles with iam:GetRole.
+	// The server needs the permission for the roles.
+	FetchRoles bool `json:"fetch_roles,omitempty"`
+}
+
+type rateLimitRedisConfig struct {
+	// Addr is the address of the server. e.g. "localhost:6379"
+	Addr string `json:"addr"`
+
+	// Username is the username for AUTH.
+	Username string `json:"username,omitempty"`
+
+	// PasswordEnv is the name of the environment value that has the password for AUTH.
+	PasswordEnv string `json:"password_env,omitempty"`
+
+	// DB is the database number.
+	DB int `json:"db,omitempty"`
+
+	// Prefix is the prefix of keys.
+	// The path prefix of each GitHub instance is appended to it.
+	Prefix string `json:"prefix,omitempty"`
+
+	// TLS enables TLS.
+	TLS bool `json:"tls,omitempty"`
+}
+
+type commitStatusConfig struct {
+	// Context is the prefix of the context of commit statuses.
+	Context string `json:"context,omitempty"`
+
+	// TargetURL is a template of the URL of the audit record.
+	TargetURL string `json:"target_url,omitempty"`
+}
+
+type webhookConfig struct {
+	// URL is the endpoint of the webhook.
+	URL string `json:"url"`
+
+	// SecretEnv is the name of the environment value that has the secret key for signing payloads.
+	SecretEnv string `json:"secret_env,omitempty"`
+
+	// Timeout is the timeout of each webhook request.
+	Timeout duration `json:"timeout,omitempty"`
+
+	// FailOpen allows issuing credentials if the webhook is unavailable.
+	FailOpen bool `json:"fail_open,omitempty"`
+}
+
+// duration is a time.Duration that is encoded as a string such as "30s".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func defaultConfig() *config {
+	return &config{
+		Listen:            ":8080",
+		ReadTimeout:       duration(10 * time.Second),
+		ReadHeaderTimeout: duration(5 * time.Second),
+		WriteTimeout:      duration(30 * time.Second),
+		IdleTimeout:       duration(120 * time.Second),
+		ShutdownDelay:     duration(5 * time.Second),
+		ShutdownTimeout:   duration(30 * time.Second),
+	}
+}
+
+func loadConfig(path string) (*config, error) {
+	cfg := defaultConfig()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	if len(cfg.GitHub) == 0 {
+		// use the default instance: github.com
+		cfg.GitHub = []*githubConfig{{}}
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *config) validate() error {
+	if cfg.TLS != nil && (cfg.TLS.CertFile == "" || cfg.TLS.KeyFile == "") {
+		return errors.New("both tls.cert_file and tls.key_file are required")
+	}
+
+	for _, gate := range cfg.DeploymentGates {
+		if len(gate.Roles) == 0 {
+			return errors.New("deployment_gates[].roles is required")
+		}
+	}
+
+	if cfg.MaxRequestBodySize < 0 {
+		return errors.New("max_request_body_size must not be negative")
+	}
+
+	if r := cfg.RateLimitRedis; r != nil {
+		if r.Addr == "" {
+			return errors.New("rate_limit_redis.addr is required")
+		}
+		if r.PasswordEnv != "" && os.Getenv(r.PasswordEnv) == "" {
+			return fmt.Errorf("the environment value %s is empty", r.PasswordEnv)
+		}
+	}
+
+	for _, wh := range cfg.Webhooks {
+		if wh.URL == "" {
+			return errors.New("webhooks[].url is required")
+		}
+	}
+
+	seen := make(map[string]struct{}, len(cfg.GitHub))
+	for _, gh := range cfg.GitHub {
+		gh.PathPrefix = strings.TrimRight(gh.PathPrefix, "/")
+		if gh.PathPrefix != "" && !strings.HasPrefix(gh.PathPrefix, "/") {
+			return fmt.Errorf("path_prefix must start with '/': %q", gh.PathPrefix)
+		}
+		if _, ok := seen[gh.PathPrefix]; ok {
+			return fmt.Errorf("duplicated path_prefix: %q", gh.PathPrefix)
+		}
+		seen[gh.PathPrefix] = struct{}{}
+		if len(gh.Policies) == 0 {
+			gh.Policies = cfg.Policies
+		}
+		if app := gh.App; app != nil {
+			if app.AppID == "" {
+				return errors.New("github[].app.app_id is required")
+			}
+			if (app.PrivateKeyFile == "") == (app.PrivateKeyEnv == "") {
+				return errors.New("either github[].app.private_key_file or github[].app.private_key_env is required")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+		"listen": ":8443",
+		"tls": {"cert_file": "cert.pem", "key_file": "key.pem"},
+		"read_timeout": "3s",
+		"github": [
+			{},
+			{
+				"path_prefix": "/ghes/",
+				"api_url": "https://ghes.example.com/api/v3",
+				"oidc_issuer": "https://ghes.example.com/_services/token",
+				"policies": [{"repositories": ["*"], "roles": ["arn:aws:iam::123456789012:role/ghes-*"]}]
+			}
+		],
+		"policies": [{"repositories": ["fuller-inc/*"], "roles": ["*"], "max_duration_seconds": 900}],
+		"github_token_auth": {"owners": ["fuller-inc"], "sunset": "2027-04-01T00:00:00Z"}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":8443" {
+		t.Errorf("unexpected listen: %q", cfg.Listen)
+	}
+	if time.Duration(cfg.ReadTimeout) != 3*time.Second {
+		t.Errorf("unexpected read timeout: %s", time.Duration(cfg.ReadTimeout))
+	}
+	if time.Duration(cfg.WriteTimeout) != 30*time.Second {
+		t.Errorf("unexpected write timeout: %s", time.Duration(cfg.WriteTimeout))
+	}
+	if len(cfg.GitHub) != 2 {
+		t.Fatalf("unexpected github instances: %d", len(cfg.GitHub))
+	}
+	if cfg.GitHub[0].Policies[0].MaxDurationSeconds != 900 {
+		t.Errorf("the top level policies should be inherited: %v", cfg.GitHub[0].Policies)
+	}
+	if cfg.GitHub[1].PathPrefix != "/ghes" {
+		t.Errorf("unexpected path prefix: %q", cfg.GitHub[1].PathPrefix)
+	}
+	if cfg.GitHub[1].Policies[0].Roles[0] != "arn:aws:iam::123456789012:role/ghes-*" {
+		t.Errorf("unexpected policies: %v", cfg.GitHub[1].Policies)
+	}
+	if auth := cfg.GitHubTokenAuth; auth == nil || !auth.Sunset.Equal(time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected github_token_auth: %v", auth)
+	}
+}
+
+func TestLoadConfig_Default(t *testing.T) {
+	cfg, err := loadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":8080" {
+		t.Errorf("unexpected listen: %q", cfg.Listen)
+	}
+	if len(cfg.GitHub) != 1 {
+		t.Errorf("unexpected github instances: %d", len(cfg.GitHub))
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	cases := []string{
+		`{"lisen": ":8080"}`,
+		`{"read_timeout": "3 seconds"}`,
+		`{"tls": {"cert_file": "cert.pem"}}`,
+		`{"github": [{"path_prefix": "ghes"}]}`,
+		`{"github": [{}, {"path_prefix": "/"}]}`,
+		`{"webhooks": [{"secret_env": "WEBHOOK_SECRET"}]}`,
+		`{"deployment_gates": [{"environments": ["production"]}]}`,
+		`{"github": [{"app": {"private_key_env": "GITHUB_APP_PRIVATE_KEY"}}]}`,
+		`{"github": [{"app": {"app_id": "123456"}}]}`,
+		`{"rate_limit_redis": {"db": 1}}`,
+		`{"max_request_body_size": -1}`,
+		`{"rate_limit_redis": {"addr": "localhost:6379", "password_env": "AWS_ASSUME_ROLE_TEST_UNDEFINED_PASSWORD"}}`,
+	}
+	for _, data := range cases {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadConfig(path); err == nil {
+			t.Errorf("%s: want error, but not", data)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// The command server runs the credential provider as a standalone HTTP(S) server.
+// It is useful for hosting the credential provider on ECS, Kubernetes, etc.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	assumerole "github.com/fuller-inc/actions-aws-assume-role/provider/assume-role"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/metrics"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/ratelimit"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/tracing"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/trustpolicy"
+)
+
+func main() {
+	var configPath string
+	flag.StringVar(&configPath, "config", "", "path to the config file")
+	flag.Parse()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))
+	slog.SetDefault(logger)
+
+	if err := serve(configPath); err != nil {
+		slog.Error("failed to serve", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+}
+
+func serve(configPath string) error {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	chSignal := make(chan os.Signal, 1)
+	signal.Notify(chSignal, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(chSignal)
+
+	tracer, err := tracing.NewFromEnv()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracing.Shutdown(ctx, tracer); err != nil {
+			slog.Warn("failed to export spans", slog.String("error", err.Error()))
+		}
+	}()
+
+	var shuttingDown atomic.Bool
+	mux, err := newServeMux(cfg, tracer)
+	if err != nil {
+		return err
+	}
+	s := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           withShutdown(mux, &shuttingDown),
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
+	}
+
+	chServe := make(chan error, 1)
+	go func() {
+		defer close(chServe)
+		slog.Info("start serving", slog.String("addr", cfg.Listen), slog.Bool("tls", cfg.TLS != nil))
+		if cfg.TLS != nil {
+			chServe <- s.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			chServe <- s.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-chServe:
+		return err
+	case sig := <-chSignal:
+		slog.Info("received a signal", slog.String("signal", sig.String()))
+	}
+	signal.Stop(chSignal)
+
+	// report that the server is not ready, and wait for load balancers to stop sending new requests.
+	shuttingDown.Store(true)
+	time.Sleep(time.Duration(cfg.ShutdownDelay))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := <-chServe; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("the server is stopped")
+	return nil
+}
+
+// withShutdown makes the readiness checks fail while the server is shutting down.
+func withShutdown(h http.Handler, shuttingDown *atomic.Bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if shuttingDown.Load() && path.Base(r.URL.Path) == "readyz" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"shutting_down"}` + "\n"))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func newServeMux(cfg *config, tracer tracing.Tracer) (*http.ServeMux, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	mux := http.NewServeMux()
+	registry := metrics.NewRegistry()
+	mux.Handle("GET /metrics", registry)
+
+	refreshSecret := []byte(os.Getenv("REFRESH_TOKEN_SECRET"))
+	adminToken := []byte(os.Getenv("ADMIN_TOKEN"))
+	var hooks []assumerole.Hook
+	for _, wh := range cfg.Webhooks {
+		var secret []byte
+		if wh.SecretEnv != "" {
+			secret = []byte(os.Getenv(wh.SecretEnv))
+		}
+		hook, err := assumerole.NewWebhookHook(&assumerole.WebhookHookConfig{
+			URL:      wh.URL,
+			Secret:   secret,
+			Timeout:  time.Duration(wh.Timeout),
+			FailOpen: wh.FailOpen,
+		})
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	var commitStatus *assumerole.CommitStatusConfig
+	if cfg.CommitStatus != nil {
+		commitStatus = &assumerole.CommitStatusConfig{
+			Context:   cfg.CommitStatus.Context,
+			TargetURL: cfg.CommitStatus.TargetURL,
+		}
+	}
+	var checkRun *assumerole.CheckRunConfig
+	if cfg.CheckRun != nil {
+		checkRun = &assumerole.CheckRunConfig{
+			Name: cfg.CheckRun.Name,
+		}
+	}
+	var iamClient trustpolicy.IAMClient
+	if cfg.TrustPolicyLinter != nil && cfg.TrustPolicyLinter.FetchRoles {
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx, tracer.AWSConfigOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		iamClient = iam.NewFromConfig(awsCfg)
+	}
+	for _, gh := range cfg.GitHub {
+		var tokens assumerole.GitHubTokenSource
+		if gh.App != nil {
+			key, err := gh.App.privateKey()
+			if err != nil {
+				return nil, err
+			}
+			tokens, err = github.NewAppTokenSource(&github.AppConfig{
+				AppID:      gh.App.AppID,
+				PrivateKey: key,
+				HTTPClient: tracer.HTTPClient(nil),
+				APIURL:     gh.APIURL,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		rateLimitStore, err := newRateLimitStore(cfg.RateLimitRedis, gh.PathPrefix)
+		if err != nil {
+			return nil, err
+		}
+		h, err := assumerole.NewHandlerWithConfig(ctx, &assumerole.Config{
+			GitHubAPIURL:       gh.APIURL,
+			GitHubOIDCIssuer:   gh.OIDCIssuer,
+			RefreshSecret:      refreshSecret,
+			Policies:           gh.Policies,
+			DeploymentGates:    cfg.DeploymentGates,
+			ProbeStrategies:    cfg.ProbeStrategies,
+			ProbeCacheTTL:      time.Duration(cfg.ProbeCacheTTL),
+			RateLimits:         cfg.RateLimits,
+			RateLimitStore:     rateLimitStore,
+			MaxRequestBodySize: cfg.MaxRequestBodySize,
+			StrictDecoding:     cfg.StrictDecoding,
+			GitHubTokenAuth:    cfg.GitHubTokenAuth,
+			AdminToken:         adminToken,
+			Metrics:            registry,
+			Tracer:             tracer,
+			Hooks:              hooks,
+			CommitStatus:       commitStatus,
+			CheckRun:           checkRun,
+			GitHubTokenSource:  tokens,
+			IAMClient:          iamClient,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if gh.PathPrefix == "" {
+			mux.Handle("/", h)
+		} else {
+			mux.Handle(gh.PathPrefix+"/", http.StripPrefix(gh.PathPrefix, h))
+		}
+	}
+	return mux, nil
+}
+
+// newRateLimitStore returns the store of the rate limits.
+// It returns nil if the buckets are stored in memory.
+func newRateLimitStore(cfg *rateLimitRedisConfig, pathPrefix string) (ratelimit.Store, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = "aws-assume-role:ratelimit:"
+	}
+	if pathPrefix != "" {
+		// the repositories of GitHub instances may have the same name.
+		prefix += pathPrefix + ":"
+	}
+	redisCfg := &ratelimit.RedisConfig{
+		Addr:     cfg.Addr,
+		Username: cfg.Username,
+		DB:       cfg.DB,
+		Prefix:   prefix,
+	}
+	if cfg.PasswordEnv != "" {
+		redisCfg.Password = os.Getenv(cfg.PasswordEnv)
+	}
+	if cfg.TLS {
+		redisCfg.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return ratelimit.NewRedisStore(redisCfg)
+}
@@ -0,0 +1,64 @@
+// The command trust-policy generates the trust policy of IAM roles for the credential provider.
+//
+//	trust-policy -repository fuller-inc/actions-aws-assume-role -use-node-id -role-session-tagging
+//
+// The ExternalId is derived in the same way as the credential provider assumes the role.
+// With -use-node-id, the GitHub token is read from the GITHUB_TOKEN environment value
+// if the credential provider has no GitHub App.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/client"
+)
+
+func main() {
+	var endpoint, repository, environment string
+	var roleSessionTagging, useNodeID bool
+	flag.StringVar(&endpoint, "provider-endpoint", client.DefaultEndpoint, "URL for credential provider")
+	flag.StringVar(&repository, "repository", "", "the repository that assumes the role. e.g. fuller-inc/actions-aws-assume-role")
+	flag.BoolVar(&useNodeID, "use-node-id", false, "use the node id of the repository instead of its name")
+	flag.BoolVar(&roleSessionTagging, "role-session-tagging", false, "enable session tagging")
+	flag.StringVar(&environment, "environment", "", "restrict the role to the environment. it requires -role-session-tagging")
+	flag.Parse()
+
+	if repository == "" {
+		fmt.Fprintln(os.Stderr, "missing required flag: -repository")
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	c, err := client.NewClient(endpoint, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	resp, err := c.TrustPolicy(ctx, &client.TrustPolicyRequest{
+		Repository:         repository,
+		UseNodeID:          useNodeID,
+		RoleSessionTagging: roleSessionTagging,
+		Environment:        environment,
+		GitHubToken:        os.Getenv("GITHUB_TOKEN"),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, resp.Policy, "", "  "); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	buf.WriteByte('\n')
+	os.Stdout.Write(buf.Bytes())
+}
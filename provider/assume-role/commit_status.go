@@ -0,0 +1,169 @@
+package assumerole
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+const (
+	// the default context of commit statuses for issuances.
+	defaultIssuanceStatusContext = "aws-assume-role/issuance"
+
+	// the maximum length of descriptions of commit statuses.
+	maxStatusDescriptionLength = 140
+)
+
+// CommitStatusConfig is configure for reporting issuances as commit statuses.
+// The commit status is posted with the installation token of the GitHub App if it is configured,
+// so the App needs the `statuses: write` permission.
+// Otherwise, or if the installation token is not available, it is posted with the GITHUB_TOKEN of the workflow,
+// so the job needs `statuses: write` permission.
+type CommitStatusConfig struct {
+	// Context is the prefix of the context of commit statuses.
+	// The AWS account ID is appended, e.g. "aws-assume-role/issuance/123456789012",
+	// so that each account has its own status.
+	// If it is empty, "aws-assume-role/issuance" is used.
+	Context string
+
+	// TargetURL is a text/template of the URL of the audit record.
+	// The fields of CommitStatusTarget are available, e.g.
+	// "https://audit.example.com/{{.Repository}}/runs/{{.RunID}}?role={{.RoleARN | urlquery}}".
+	// If it is empty, the status has no target URL.
+	TargetURL string
+}
+
+// CommitStatusTarget is the data for rendering CommitStatusConfig.TargetURL.
+type CommitStatusTarget struct {
+	Repository      string
+	SHA             string
+	RunID           string
+	RunAttempt      string
+	RoleARN         string
+	RoleSessionName string
+	Account         string
+	Expiration      time.Time
+}
+
+type commitStatusReporter struct {
+	context   string
+	targetURL *template.Template
+}
+
+func newCommitStatusReporter(cfg *CommitStatusConfig) (*commitStatusReporter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	r := &commitStatusReporter{
+		context: cfg.Context,
+	}
+	if r.context == "" {
+		r.context = defaultIssuanceStatusContext
+	}
+	if cfg.TargetURL != "" {
+		tmpl, err := template.New("target_url").Option("missingkey=error").Parse(cfg.TargetURL)
+		if err != nil {
+			return nil, fmt.Errorf("commit status: failed to parse the target url: %w", err)
+		}
+		r.targetURL = tmpl
+	}
+	return r, nil
+}
+
+// reportCommitStatus posts the result of the issuance as a commit status.
+// Failures are only logged, because the credentials are already issued.
+func (h *Handler) reportCommitStatus(ctx context.Context, idToken *github.ActionsIDToken, req *requestBody, result *IssueResult) {
+	r := h.commitStatus
+	if r == nil {
+		return
+	}
+	target := &CommitStatusTarget{
+		Repository:      req.Repository,
+		SHA:             req.SHA,
+		RunID:           req.RunID,
+		RoleARN:         req.RoleToAssume,
+		RoleSessionName: req.RoleSessionName,
+		Expiration:      result.Expiration,
+	}
+	if idToken != nil {
+		target.Repository = idToken.Repository
+		target.SHA = idToken.SHA
+		target.RunID = idToken.RunID
+		target.RunAttempt = idToken.RunAttempt
+	}
+	if a, err := arn.Parse(req.RoleToAssume); err == nil {
+		target.Account = a.AccountID
+	}
+
+	owner, repo, err := splitOwnerRepo(target.Repository)
+	if err != nil {
+		return
+	}
+	token := h.githubToken(ctx, owner, repo, req)
+	if token == "" {
+		h.log().InfoContext(ctx, "GITHUB_TOKEN is not available, skip reporting the commit status")
+		return
+	}
+	status, err := r.status(target, result.Err)
+	if err == nil {
+		_, err = h.github.CreateStatus(ctx, token, owner, repo, target.SHA, status)
+	}
+	if err != nil {
+		h.log().WarnContext(ctx, "failed to report the commit status", slog.String("error", err.Error()))
+	}
+}
+
+func (r *commitStatusReporter) status(target *CommitStatusTarget, issueErr error) (*github.CreateStatusRequest, error) {
+	status := &github.CreateStatusRequest{
+		Context: r.context,
+	}
+	if target.Account != "" {
+		status.Context += "/" + target.Account
+	}
+
+	if issueErr == nil {
+		status.State = github.CommitStateSuccess
+		status.Description = fmt.Sprintf("Assumed %s, expires at %s", target.RoleARN, target.Expiration.UTC().Format(time.RFC3339))
+	} else {
+		var validation *validationError
+		var veto *VetoError
+		if errors.As(issueErr, &validation) || errors.As(issueErr, &veto) {
+			status.State = github.CommitStateFailure
+		} else {
+			status.State = github.CommitStateError
+		}
+		status.Description = fmt.Sprintf("Failed to assume %s", target.RoleARN)
+	}
+	status.Description = truncateDescription(status.Description)
+
+	if r.targetURL != nil {
+		var buf strings.Builder
+		if err := r.targetURL.Execute(&buf, target); err != nil {
+			return nil, fmt.Errorf("commit status: failed to render the target url: %w", err)
+		}
+		status.TargetURL = buf.String()
+	}
+	return status, nil
+}
+
+// truncateDescription truncates s to the limit of GitHub.
+func truncateDescription(s string) string {
+	if len(s) <= maxStatusDescriptionLength {
+		return s
+	}
+	var n int
+	for i := range s {
+		if i > maxStatusDescriptionLength-len("...") {
+			break
+		}
+		n = i
+	}
+	return s[:n] + "..."
+}
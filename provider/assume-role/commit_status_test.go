@@ -0,0 +1,128 @@
+package assumerole
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+// githubClientStatusRecorder records the commit statuses.
+type githubClientStatusRecorder struct {
+	githubClientDummy
+	statuses []*github.CreateStatusRequest
+}
+
+func (c *githubClientStatusRecorder) CreateStatus(ctx context.Context, token, owner, repo, ref string, status *github.CreateStatusRequest) (*github.CreateStatusResponse, error) {
+	c.statuses = append(c.statuses, status)
+	return c.githubClientDummy.CreateStatus(ctx, token, owner, repo, ref, status)
+}
+
+func TestCommitStatus(t *testing.T) {
+	client := &githubClientStatusRecorder{}
+	h, err := NewHandlerWithOptions(
+		WithGitHubClient(client),
+		WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+			return &stsClientDummy{}, nil
+		}),
+		WithCommitStatus(&CommitStatusConfig{
+			TargetURL: "https://audit.example.com/{{.Repository}}/runs/{{.RunID}}?account={{.Account}}",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := strings.Replace(dummyAssumeRoleRequest, `"id_token"`, `"github_token": "ghs_dummyGitHubToken", "id_token"`, 1)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	if len(client.statuses) != 1 {
+		t.Fatalf("want 1 status, got %d", len(client.statuses))
+	}
+	status := client.statuses[0]
+	if status.State != github.CommitStateSuccess {
+		t.Errorf("unexpected state: %q", status.State)
+	}
+	if want := "aws-assume-role/issuance/123456789012"; status.Context != want {
+		t.Errorf("unexpected context: want %q, got %q", want, status.Context)
+	}
+	if !strings.HasPrefix(status.Description, "Assumed arn:aws:iam::123456789012:role/assume-role-test") {
+		t.Errorf("unexpected description: %q", status.Description)
+	}
+	if want := "https://audit.example.com/fuller-inc/actions-aws-assume-role/runs/1234567890?account=123456789012"; status.TargetURL != want {
+		t.Errorf("unexpected target url: want %q, got %q", want, status.TargetURL)
+	}
+}
+
+func TestCommitStatus_WithoutToken(t *testing.T) {
+	client := &githubClientStatusRecorder{}
+	h, err := NewHandlerWithOptions(
+		WithGitHubClient(client),
+		WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+			return &stsClientDummy{}, nil
+		}),
+		WithCommitStatus(&CommitStatusConfig{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(dummyAssumeRoleRequest))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+	if len(client.statuses) != 0 {
+		t.Errorf("want no status, got %d", len(client.statuses))
+	}
+}
+
+func TestCommitStatusReporter_Status(t *testing.T) {
+	r, err := newCommitStatusReporter(&CommitStatusConfig{Context: "audit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := &CommitStatusTarget{
+		RoleARN:    "arn:aws:iam::123456789012:role/" + strings.Repeat("very-long-role-name-", 10),
+		Account:    "123456789012",
+		Expiration: time.Date(2026, 10, 19, 1, 0, 0, 0, time.UTC),
+	}
+
+	status, err := r.status(target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Description) > maxStatusDescriptionLength {
+		t.Errorf("the description is too long: %d", len(status.Description))
+	}
+	if !strings.HasSuffix(status.Description, "...") {
+		t.Errorf("the description should be truncated: %q", status.Description)
+	}
+
+	status, err = r.status(target, &validationError{message: "AccessDenied"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != github.CommitStateFailure {
+		t.Errorf("unexpected state: want %q, got %q", github.CommitStateFailure, status.State)
+	}
+	if status.Context != "audit/123456789012" {
+		t.Errorf("unexpected context: %q", status.Context)
+	}
+}
+
+func TestNewCommitStatusReporter_InvalidTemplate(t *testing.T) {
+	if _, err := newCommitStatusReporter(&CommitStatusConfig{TargetURL: "{{.RunID"}); err == nil {
+		t.Error("want error, but not")
+	}
+}
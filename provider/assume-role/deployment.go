@@ -0,0 +1,164 @@
+package assumerole
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+// DeploymentGate requires approved deployments for assuming the roles.
+// The job must run in a GitHub environment protected by required reviewers,
+// and the deployment of the run must be approved by them.
+type DeploymentGate struct {
+	// Roles is a list of patterns of role ARNs that require approved deployments.
+	Roles []string `json:"roles"`
+
+	// Environments is a list of patterns of environment names that can assume the roles.
+	// If it is empty, any environment protected by required reviewers can.
+	Environments []string `json:"environments,omitempty"`
+}
+
+// checkDeployment checks whether the job is deployed to an approved environment,
+// if a deployment gate is configured for the role.
+func (h *Handler) checkDeployment(ctx context.Context, idToken *github.ActionsIDToken, req *requestBody) error {
+	var gates []*DeploymentGate
+	for i := range h.deploymentGates {
+		if matchAny(h.deploymentGates[i].Roles, req.RoleToAssume) {
+			gates = append(gates, &h.deploymentGates[i])
+		}
+	}
+	if len(gates) == 0 {
+		return nil
+	}
+
+	if idToken == nil {
+		return &validationError{
+			message: fmt.Sprintf("%s requires an approved deployment, but the OIDC token is not available. "+
+				"Add `id-token: write` to the permissions of the job.", req.RoleToAssume),
+			code: "DeploymentRequired",
+		}
+	}
+	env := idToken.Environment
+	if env == "" {
+		return &validationError{
+			message: fmt.Sprintf("%s requires an approved deployment, but the job doesn't run in any environment. "+
+				"Set `environment` of the job.", req.RoleToAssume),
+			code: "DeploymentRequired",
+		}
+	}
+	for _, gate := range gates {
+		if len(gate.Environments) > 0 && !matchAny(gate.Environments, env) {
+			return &validationError{
+				message: fmt.Sprintf("the environment %q is not allowed to assume %s", env, req.RoleToAssume),
+				code:    "DeploymentRequired",
+			}
+		}
+	}
+
+	owner, repo, err := splitOwnerRepo(idToken.Repository)
+	if err != nil {
+		return err
+	}
+	token := h.githubToken(ctx, owner, repo, req)
+	if token == "" {
+		return &validationError{
+			message: fmt.Sprintf("%s requires an approved deployment, but GITHUB_TOKEN is not available to verify it", req.RoleToAssume),
+			code:    "DeploymentRequired",
+		}
+	}
+
+	// the environment must be protected by required reviewers.
+	// Environments referred by jobs are created automatically without any protection rule.
+	environment, err := h.github.GetEnvironment(ctx, token, owner, repo, env)
+	if err != nil {
+		return deploymentAPIError(err, fmt.Sprintf("the environment %q is not found", env))
+	}
+	var protected bool
+	for _, rule := range environment.ProtectionRules {
+		if rule.Type == github.ProtectionRuleTypeRequiredReviewers {
+			protected = true
+		}
+	}
+	if !protected {
+		return &validationError{
+			message: fmt.Sprintf("%s requires an approved deployment, but the environment %q is not protected by required reviewers", req.RoleToAssume, env),
+			code:    "DeploymentNotProtected",
+		}
+	}
+
+	// the deployment of the run must be reviewed and approved.
+	approvals, err := h.github.ListWorkflowRunApprovals(ctx, token, owner, repo, idToken.RunID)
+	if err != nil {
+		return deploymentAPIError(err, fmt.Sprintf("the workflow run %s is not found", idToken.RunID))
+	}
+	var approved bool
+	for _, approval := range approvals {
+		if !approvalHasEnvironment(approval, env) {
+			continue
+		}
+		switch approval.State {
+		case github.ApprovalStateApproved:
+			approved = true
+		case github.ApprovalStateRejected:
+			return &validationError{
+				message: fmt.Sprintf("the deployment to the environment %q is rejected", env),
+				code:    "DeploymentRejected",
+			}
+		}
+	}
+	if !approved {
+		return &validationError{
+			message: fmt.Sprintf("the job bypassed the protection of the environment %q: no approved review is found in the workflow run %s", env, idToken.RunID),
+			code:    "DeploymentNotApproved",
+		}
+	}
+
+	// the commit must be deployed to the environment.
+	deployments, err := h.github.ListDeployments(ctx, token, owner, repo, idToken.SHA, env)
+	if err != nil {
+		return deploymentAPIError(err, fmt.Sprintf("the deployments of %s are not found", idToken.SHA))
+	}
+	for _, d := range deployments {
+		if d.Environment == env && d.SHA == idToken.SHA {
+			return nil
+		}
+	}
+	return &validationError{
+		message: fmt.Sprintf("the job bypassed the protection of the environment %q: no deployment of %s is found", env, idToken.SHA),
+		code:    "DeploymentNotApproved",
+	}
+}
+
+func approvalHasEnvironment(approval *github.WorkflowRunApproval, env string) bool {
+	for _, e := range approval.Environments {
+		if e.Name == env {
+			return true
+		}
+	}
+	return false
+}
+
+// deploymentAPIError converts the client errors of GitHub API into validation errors.
+func deploymentAPIError(err error, notFound string) error {
+	var githubErr *github.UnexpectedStatusCodeError
+	if !errors.As(err, &githubErr) {
+		return err
+	}
+	switch {
+	case githubErr.StatusCode == http.StatusNotFound:
+		return &validationError{
+			message: notFound + ", or your GITHUB_TOKEN doesn't have enough permission. " +
+				"`actions: read` and `deployments: read` are required.",
+			code: "DeploymentRequired",
+		}
+	case 400 <= githubErr.StatusCode && githubErr.StatusCode < 500:
+		return &validationError{
+			message: "Your GITHUB_TOKEN doesn't have enough permission. `actions: read` and `deployments: read` are required.",
+			code:    "DeploymentRequired",
+		}
+	}
+	return err
+}
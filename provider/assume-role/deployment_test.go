@@ -0,0 +1,211 @@
+package assumerole
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+	"github.com/shogo82148/goat/jwt"
+)
+
+func newDeploymentTestHandler(env *github.GetEnvironmentResponse, approvals []*github.WorkflowRunApproval, deployments []*github.Deployment) *Handler {
+	return &Handler{
+		github: &githubClientMock{
+			GetEnvironmentFunc: func(ctx context.Context, token, owner, repo, environment string) (*github.GetEnvironmentResponse, error) {
+				if env == nil || environment != env.Name {
+					return nil, &github.UnexpectedStatusCodeError{StatusCode: http.StatusNotFound}
+				}
+				return env, nil
+			},
+			ListWorkflowRunApprovalsFunc: func(ctx context.Context, token, owner, repo, runID string) ([]*github.WorkflowRunApproval, error) {
+				return approvals, nil
+			},
+			ListDeploymentsFunc: func(ctx context.Context, token, owner, repo, sha, environment string) ([]*github.Deployment, error) {
+				return deployments, nil
+			},
+		},
+		deploymentGates: []DeploymentGate{
+			{
+				Roles:        []string{"arn:aws:iam::123456789012:role/deploy-*"},
+				Environments: []string{"production", "staging"},
+			},
+		},
+	}
+}
+
+func newDeploymentTestIDToken(environment string) *github.ActionsIDToken {
+	return &github.ActionsIDToken{
+		Claims: &jwt.Claims{
+			Subject: "repo:fuller-inc/actions-aws-assume-role:environment:" + environment,
+		},
+		Repository:  "fuller-inc/actions-aws-assume-role",
+		RunID:       "1234567890",
+		SHA:         "e3a45c6c16c1464826b36a598ff39e6cc98c4da4",
+		Environment: environment,
+	}
+}
+
+var protectedEnvironment = &github.GetEnvironmentResponse{
+	Name: "production",
+	ProtectionRules: []*github.GetEnvironmentResponseRule{
+		{Type: github.ProtectionRuleTypeWaitTimer, WaitTimer: 30},
+		{Type: github.ProtectionRuleTypeRequiredReviewers},
+	},
+}
+
+func approvalFor(state github.ApprovalState, environment string) *github.WorkflowRunApproval {
+	return &github.WorkflowRunApproval{
+		State: state,
+		Environments: []*github.WorkflowRunApprovalEnvironment{
+			{Name: environment},
+		},
+		User: &github.WorkflowRunApprovalUser{Login: "shogo82148"},
+	}
+}
+
+var productionDeployments = []*github.Deployment{
+	{
+		ID:          1,
+		SHA:         "e3a45c6c16c1464826b36a598ff39e6cc98c4da4",
+		Environment: "production",
+	},
+}
+
+func TestCheckDeployment(t *testing.T) {
+	h := newDeploymentTestHandler(
+		protectedEnvironment,
+		[]*github.WorkflowRunApproval{approvalFor(github.ApprovalStateApproved, "production")},
+		productionDeployments,
+	)
+	req := &requestBody{
+		RoleToAssume: "arn:aws:iam::123456789012:role/deploy-production",
+		GitHubToken:  "ghs_dummyGitHubToken",
+	}
+	if err := h.checkDeployment(context.Background(), newDeploymentTestIDToken("production"), req); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckDeployment_NotGated(t *testing.T) {
+	h := &Handler{
+		// all GitHub API calls panic.
+		github: &githubClientMock{},
+		deploymentGates: []DeploymentGate{
+			{Roles: []string{"arn:aws:iam::123456789012:role/deploy-*"}},
+		},
+	}
+	req := &requestBody{
+		RoleToAssume: "arn:aws:iam::123456789012:role/read-only",
+	}
+	if err := h.checkDeployment(context.Background(), nil, req); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckDeployment_Fail(t *testing.T) {
+	cases := []struct {
+		name        string
+		env         *github.GetEnvironmentResponse
+		approvals   []*github.WorkflowRunApproval
+		deployments []*github.Deployment
+		idToken     *github.ActionsIDToken
+		code        string
+	}{
+		{
+			name:    "github token",
+			idToken: nil,
+			code:    "DeploymentRequired",
+		},
+		{
+			name:    "no environment",
+			idToken: newDeploymentTestIDToken(""),
+			code:    "DeploymentRequired",
+		},
+		{
+			name:    "environment not allowed",
+			env:     protectedEnvironment,
+			idToken: newDeploymentTestIDToken("development"),
+			code:    "DeploymentRequired",
+		},
+		{
+			name:    "environment not found",
+			env:     nil,
+			idToken: newDeploymentTestIDToken("production"),
+			code:    "DeploymentRequired",
+		},
+		{
+			name: "not protected",
+			env: &github.GetEnvironmentResponse{
+				Name: "production",
+				ProtectionRules: []*github.GetEnvironmentResponseRule{
+					{Type: github.ProtectionRuleTypeBranchPolicy},
+				},
+			},
+			idToken: newDeploymentTestIDToken("production"),
+			code:    "DeploymentNotProtected",
+		},
+		{
+			name:        "no approval",
+			env:         protectedEnvironment,
+			approvals:   []*github.WorkflowRunApproval{approvalFor(github.ApprovalStateApproved, "staging")},
+			deployments: productionDeployments,
+			idToken:     newDeploymentTestIDToken("production"),
+			code:        "DeploymentNotApproved",
+		},
+		{
+			name: "rejected",
+			env:  protectedEnvironment,
+			approvals: []*github.WorkflowRunApproval{
+				approvalFor(github.ApprovalStateApproved, "production"),
+				approvalFor(github.ApprovalStateRejected, "production"),
+			},
+			deployments: productionDeployments,
+			idToken:     newDeploymentTestIDToken("production"),
+			code:        "DeploymentRejected",
+		},
+		{
+			name:        "no deployment",
+			env:         protectedEnvironment,
+			approvals:   []*github.WorkflowRunApproval{approvalFor(github.ApprovalStateApproved, "production")},
+			deployments: []*github.Deployment{},
+			idToken:     newDeploymentTestIDToken("production"),
+			code:        "DeploymentNotApproved",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newDeploymentTestHandler(tc.env, tc.approvals, tc.deployments)
+			req := &requestBody{
+				RoleToAssume: "arn:aws:iam::123456789012:role/deploy-production",
+				GitHubToken:  "ghs_dummyGitHubToken",
+			}
+			err := h.checkDeployment(context.Background(), tc.idToken, req)
+			var validation *validationError
+			if !errors.As(err, &validation) {
+				t.Fatalf("want validation error, got %v", err)
+			}
+			if validation.code != tc.code {
+				t.Errorf("unexpected code: want %q, got %q: %s", tc.code, validation.code, validation.message)
+			}
+		})
+	}
+}
+
+func TestCheckDeployment_ServerError(t *testing.T) {
+	h := newDeploymentTestHandler(protectedEnvironment, nil, nil)
+	h.github.(*githubClientMock).ListWorkflowRunApprovalsFunc = func(ctx context.Context, token, owner, repo, runID string) ([]*github.WorkflowRunApproval, error) {
+		return nil, &github.UnexpectedStatusCodeError{StatusCode: http.StatusBadGateway}
+	}
+	req := &requestBody{
+		RoleToAssume: "arn:aws:iam::123456789012:role/deploy-production",
+		GitHubToken:  "ghs_dummyGitHubToken",
+	}
+	err := h.checkDeployment(context.Background(), newDeploymentTestIDToken("production"), req)
+	var validation *validationError
+	if err == nil || errors.As(err, &validation) {
+		t.Errorf("want internal error, got %v", err)
+	}
+}
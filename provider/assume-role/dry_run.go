@@ -0,0 +1,146 @@
+package assumerole
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+const (
+	nodeIDFormatNext   = "next"
+	nodeIDFormatLegacy = "legacy"
+)
+
+// explanation describes how the credential provider would assume the role.
+// It is returned instead of credentials in dry run mode.
+type explanation struct {
+	// CredentialType is the type of the credential that authenticated the request: "oidc" or "github_token".
+	CredentialType string `json:"credential_type"`
+
+	Repository      string `json:"repository"`
+	RoleARN         string `json:"role_arn"`
+	RoleSessionName string `json:"role_session_name"`
+	DurationSeconds int32  `json:"duration_seconds"`
+
+	// NodeIDFormat is the ID format that is tried first. It is empty if the request doesn't use node IDs.
+	NodeIDFormat string `json:"node_id_format,omitempty"`
+
+	ExternalID string            `json:"external_id"`
+	Tags       []*explanationTag `json:"tags,omitempty"`
+
+	// Fallback is tried if AWS denies the first attempt.
+	Fallback *explanationFallback `json:"fallback,omitempty"`
+
+	// Probe is the result of the negative ExternalId probe. It is nil if the probe is not requested.
+	Probe *explanationProbe `json:"probe,omitempty"`
+}
+
+type explanationTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type explanationFallback struct {
+	NodeIDFormat string            `json:"node_id_format"`
+	ExternalID   string            `json:"external_id,omitempty"`
+	Tags         []*explanationTag `json:"tags,omitempty"`
+
+	// Message is the reason why the fallback is unavailable.
+	// The fallback is needed only if AWS denies the first attempt, so it doesn't fail the explanation.
+	Message string `json:"message,omitempty"`
+}
+
+type explanationProbe struct {
+	// Passed is true if AWS denied the request without the correct ExternalId.
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// explain authorizes the authenticated request and explains how the role would be assumed,
+// without issuing credentials.
+func (h *Handler) explain(ctx context.Context, idToken *github.ActionsIDToken, req *requestBody) (*responseBody, error) {
+	repository := req.Repository
+	credentialType := "github_token"
+	if idToken != nil {
+		repository = idToken.Repository
+		credentialType = "oidc"
+	}
+	if err := h.authorize(repository, req.RoleToAssume, req.DurationSeconds); err != nil {
+		return nil, err
+	}
+	if err := h.checkDeployment(ctx, idToken, req); err != nil {
+		return nil, err
+	}
+
+	var legacy <-chan assumeRoleInputResult
+	if req.UseNodeID {
+		// look up both formats concurrently.
+		legacyCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		legacy = h.assumeRoleInputAsync(legacyCtx, false, idToken, req)
+	}
+
+	input, err := h.assumeRoleInput(ctx, true, idToken, req)
+	if err != nil {
+		return nil, err
+	}
+	e := &explanation{
+		CredentialType:  credentialType,
+		Repository:      repository,
+		RoleARN:         req.RoleToAssume,
+		RoleSessionName: req.RoleSessionName,
+		DurationSeconds: req.DurationSeconds,
+		ExternalID:      aws.ToString(input.ExternalId),
+		Tags:            explainTags(input),
+	}
+	if req.UseNodeID {
+		e.NodeIDFormat = nodeIDFormatNext
+		result := <-legacy
+		if result.err != nil {
+			h.log().WarnContext(ctx, "failed to explain the fallback", slog.String("error", result.err.Error()))
+			e.Fallback = &explanationFallback{
+				NodeIDFormat: nodeIDFormatLegacy,
+				Message:      "the legacy node id is unavailable: " + result.err.Error(),
+			}
+		} else if legacy := result.input; aws.ToString(legacy.ExternalId) != e.ExternalID {
+			e.Fallback = &explanationFallback{
+				NodeIDFormat: nodeIDFormatLegacy,
+				ExternalID:   aws.ToString(legacy.ExternalId),
+				Tags:         explainTags(legacy),
+			}
+		}
+	}
+
+	if req.Probe {
+		e.Probe = &explanationProbe{Passed: true}
+		if err := h.runProbes(ctx, input); err != nil {
+			var validation *validationError
+			if !errors.As(err, &validation) {
+				return nil, err
+			}
+			e.Probe = &explanationProbe{
+				Passed:  false,
+				Message: validation.message,
+			}
+		}
+	}
+
+	return &responseBody{
+		Explanation: e,
+	}, nil
+}
+
+func explainTags(input *sts.AssumeRoleInput) []*explanationTag {
+	tags := make([]*explanationTag, 0, len(input.Tags))
+	for _, tag := range input.Tags {
+		tags = append(tags, &explanationTag{
+			Key:   aws.ToString(tag.Key),
+			Value: aws.ToString(tag.Value),
+		})
+	}
+	return tags
+}
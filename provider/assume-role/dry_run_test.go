@@ -0,0 +1,219 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+const dummyDryRunRequest = `{
+	"id_token": "dummyGitHubIDToken",
+	"role_to_assume": "arn:aws:iam::123456789012:role/assume-role-test",
+	"role_session_name": "GitHubActions",
+	"duration_seconds": 900,
+	"repository": "fuller-inc/actions-aws-assume-role",
+	"use_node_id": true,
+	"role_session_tagging": true,
+	"sha": "e3a45c6c16c1464826b36a598ff39e6cc98c4da4",
+	"run_id": "1234567890",
+	"workflow": "test",
+	"actor": "fuller-inc",
+	"dry_run": true,
+	"probe": true
+}`
+
+func newDryRunTestHandler(t *testing.T, assumeRole func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error)) *Handler {
+	t.Helper()
+	h, err := NewHandlerWithOptions(
+		WithGitHubClient(&githubClientDummy{}),
+		WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+			return &stsClientMock{
+				AssumeRoleFunc: assumeRole,
+			}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestDryRun(t *testing.T) {
+	var inputs []*sts.AssumeRoleInput
+	h := newDryRunTestHandler(t, func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+		inputs = append(inputs, params)
+		return nil, errAccessDenied
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(dummyDryRunRequest))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	var resp responseBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.AccessKeyId != "" || resp.SessionToken != "" {
+		t.Errorf("credentials are issued in dry run mode: %s", w.Body.String())
+	}
+	e := resp.Explanation
+	if e == nil {
+		t.Fatal("explanation is missing")
+	}
+	if e.CredentialType != "oidc" {
+		t.Errorf("unexpected credential type: %q", e.CredentialType)
+	}
+	if e.NodeIDFormat != "next" {
+		t.Errorf("unexpected node id format: %q", e.NodeIDFormat)
+	}
+	if e.ExternalID != "R_kgDOFMsDjw" {
+		t.Errorf("unexpected external id: %q", e.ExternalID)
+	}
+	if e.Fallback == nil || e.Fallback.ExternalID != "MDEwOlJlcG9zaXRvcnkzNDg4NDkwMzk=" {
+		t.Errorf("unexpected fallback: %#v", e.Fallback)
+	}
+	var repository string
+	for _, tag := range e.Tags {
+		if tag.Key == "Repository" {
+			repository = tag.Value
+		}
+	}
+	if repository != "R_kgDOFMsDjw" {
+		t.Errorf("unexpected Repository tag: %q", repository)
+	}
+	if e.Probe == nil || !e.Probe.Passed {
+		t.Errorf("unexpected probe result: %#v", e.Probe)
+	}
+
+	// only the probes are sent to AWS.
+	if len(inputs) != 2 {
+		t.Fatalf("want 2 AssumeRole calls, got %d", len(inputs))
+	}
+	if inputs[0].ExternalId != nil {
+		t.Errorf("the probe must not have the external id: %q", aws.ToString(inputs[0].ExternalId))
+	}
+	if id := aws.ToString(inputs[1].ExternalId); !strings.HasPrefix(id, probeExternalIDPrefix) {
+		t.Errorf("unexpected external id of the probe: %q", id)
+	}
+}
+
+func TestDryRun_TooOpen(t *testing.T) {
+	h := newDryRunTestHandler(t, func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+		return &sts.AssumeRoleOutput{
+			Credentials: &types.Credentials{},
+		}, nil
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(dummyDryRunRequest))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp responseBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if p := resp.Explanation.Probe; p == nil || p.Passed || !strings.Contains(p.Message, "too open") {
+		t.Errorf("unexpected probe result: %#v", p)
+	}
+}
+
+func TestDryRun_WithoutProbe(t *testing.T) {
+	h := newDryRunTestHandler(t, func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+		t.Error("AssumeRole must not be called")
+		return nil, errAccessDenied
+	})
+
+	body := strings.Replace(dummyDryRunRequest, `"probe": true`, `"probe": false`, 1)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp responseBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Explanation.Probe != nil {
+		t.Errorf("unexpected probe result: %#v", resp.Explanation.Probe)
+	}
+}
+
+// githubClientLegacyIDError fails to look up the legacy node IDs.
+type githubClientLegacyIDError struct {
+	githubClientDummy
+}
+
+func (c *githubClientLegacyIDError) GetRepo(ctx context.Context, nextIDFormat bool, token, owner, repo string) (*github.GetRepoResponse, error) {
+	if !nextIDFormat {
+		return nil, errors.New("unexpected status code: 502")
+	}
+	return c.githubClientDummy.GetRepo(ctx, nextIDFormat, token, owner, repo)
+}
+
+func TestDryRun_FallbackUnavailable(t *testing.T) {
+	h, err := NewHandlerWithOptions(
+		WithGitHubClient(&githubClientLegacyIDError{}),
+		WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+			return &stsClientMock{
+				AssumeRoleFunc: func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+					return nil, errAccessDenied
+				},
+			}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the role would be assumed with the next format, so the explanation doesn't fail.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(dummyDryRunRequest))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp responseBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	e := resp.Explanation
+	if e == nil || e.ExternalID != "R_kgDOFMsDjw" {
+		t.Fatalf("unexpected explanation: %#v", e)
+	}
+	if f := e.Fallback; f == nil || f.NodeIDFormat != "legacy" || f.ExternalID != "" || !strings.Contains(f.Message, "unavailable") {
+		t.Errorf("unexpected fallback: %#v", f)
+	}
+}
+
+func TestDryRun_Invalid(t *testing.T) {
+	h := newDryRunTestHandler(t, nil)
+	cases := []string{
+		// probe is available only in dry run mode
+		strings.Replace(dummyDryRunRequest, `"dry_run": true`, `"dry_run": false`, 1),
+		// dry run doesn't return credentials
+		strings.Replace(dummyDryRunRequest, `"dry_run": true`, `"dry_run": true, "output_format": "credential_process"`, 1),
+	}
+	for _, body := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+		}
+	}
+}
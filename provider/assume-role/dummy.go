@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
@@ -37,6 +38,16 @@ func (c *githubClientDummy) CreateStatus(ctx context.Context, token, owner, repo
 	}, nil
 }
 
+func (c *githubClientDummy) CreateCheckRun(ctx context.Context, token, owner, repo string, run *github.CreateCheckRunRequest) (*github.CreateCheckRunResponse, error) {
+	if owner != "fuller-inc" || repo != "actions-aws-assume-role" || run.HeadSHA != "e3a45c6c16c1464826b36a598ff39e6cc98c4da4" {
+		return nil, &github.UnexpectedStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+	return &github.CreateCheckRunResponse{
+		ID:      4,
+		HTMLURL: "https://github.com/fuller-inc/actions-aws-assume-role/runs/4",
+	}, nil
+}
+
 func (c *githubClientDummy) GetRepo(ctx context.Context, nextIDFormat bool, token, owner, repo string) (*github.GetRepoResponse, error) {
 	if nextIDFormat {
 		return &github.GetRepoResponse{
@@ -59,6 +70,54 @@ func (c *githubClientDummy) GetUser(ctx context.Context, nextIDFormat bool, toke
 	}, nil
 }
 
+func (c *githubClientDummy) GetEnvironment(ctx context.Context, token, owner, repo, environment string) (*github.GetEnvironmentResponse, error) {
+	if token != "ghs_dummyGitHubToken" || owner != "fuller-inc" || repo != "actions-aws-assume-role" || environment != "production" {
+		return nil, &github.UnexpectedStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+	return &github.GetEnvironmentResponse{
+		ID:   161088068,
+		Name: "production",
+		ProtectionRules: []*github.GetEnvironmentResponseRule{
+			{
+				ID:   3755,
+				Type: github.ProtectionRuleTypeRequiredReviewers,
+			},
+		},
+	}, nil
+}
+
+func (c *githubClientDummy) ListDeployments(ctx context.Context, token, owner, repo, sha, environment string) ([]*github.Deployment, error) {
+	if token != "ghs_dummyGitHubToken" || owner != "fuller-inc" || repo != "actions-aws-assume-role" {
+		return nil, &github.UnexpectedStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+	if sha != "e3a45c6c16c1464826b36a598ff39e6cc98c4da4" || environment != "production" {
+		return []*github.Deployment{}, nil
+	}
+	return []*github.Deployment{
+		{
+			ID:          1,
+			SHA:         sha,
+			Ref:         "main",
+			Environment: environment,
+		},
+	}, nil
+}
+
+func (c *githubClientDummy) ListWorkflowRunApprovals(ctx context.Context, token, owner, repo, runID string) ([]*github.WorkflowRunApproval, error) {
+	if token != "ghs_dummyGitHubToken" || owner != "fuller-inc" || repo != "actions-aws-assume-role" || runID != "1234567890" {
+		return nil, &github.UnexpectedStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+	return []*github.WorkflowRunApproval{
+		{
+			State: github.ApprovalStateApproved,
+			Environments: []*github.WorkflowRunApprovalEnvironment{
+				{ID: 161088068, Name: "production"},
+			},
+			User: &github.WorkflowRunApprovalUser{Login: "shogo82148", ID: 1157344},
+		},
+	}, nil
+}
+
 func (c *githubClientDummy) ParseIDToken(ctx context.Context, idToken string) (*github.ActionsIDToken, error) {
 	if idToken != "dummyGitHubIDToken" {
 		return nil, errors.New("invalid id token")
@@ -71,6 +130,7 @@ func (c *githubClientDummy) ParseIDToken(ctx context.Context, idToken string) (*
 		Repository: "fuller-inc/actions-aws-assume-role",
 		Workflow:   "test",
 		RunID:      "1234567890",
+		RunAttempt: "1",
 		Actor:      "fuller-inc",
 		SHA:        "e3a45c6c16c1464826b36a598ff39e6cc98c4da4",
 		Ref:        "refs/heads/main",
@@ -81,10 +141,18 @@ func (c *githubClientDummy) ValidateAPIURL(url string) error {
 	return nil
 }
 
+func (c *githubClientDummy) CheckJWKS(ctx context.Context) error {
+	return nil
+}
+
+func (c *githubClientDummy) Ping(ctx context.Context) error {
+	return nil
+}
+
 type stsClientDummy struct{}
 
 func (c *stsClientDummy) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
-	if params.ExternalId == nil {
+	if params.ExternalId == nil || strings.HasPrefix(*params.ExternalId, probeExternalIDPrefix) {
 		return nil, errAccessDenied
 	}
 	return &sts.AssumeRoleOutput{
@@ -96,9 +164,18 @@ func (c *stsClientDummy) AssumeRole(ctx context.Context, params *sts.AssumeRoleI
 	}, nil
 }
 
+func (c *stsClientDummy) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String("053160724612"),
+		Arn:     aws.String("arn:aws:sts::053160724612:assumed-role/aws-assume-role-ProviderFunction/dummy"),
+		UserId:  aws.String("AROAEXAMPLEEXAMPLE:dummy"),
+	}, nil
+}
+
 func NewDummyHandler() *Handler {
 	return &Handler{
-		github: &githubClientDummy{},
-		sts:    &stsClientDummy{},
+		github:        &githubClientDummy{},
+		sts:           &stsClientDummy{},
+		refreshSecret: []byte("dummyRefreshTokenSecret"),
 	}
 }
@@ -0,0 +1,141 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shogo82148/goat/jwa"
+	"github.com/shogo82148/goat/jwk"
+	"github.com/shogo82148/goat/jws"
+	"github.com/shogo82148/goat/jwt"
+	"github.com/shogo82148/goat/sig"
+	"github.com/shogo82148/memoize"
+)
+
+const (
+	// the lifetime of JWTs of GitHub Apps. GitHub accepts up to 10 minutes.
+	appJWTLifetime = 9 * time.Minute
+
+	// the allowance of the clock drift between the provider and GitHub.
+	appJWTClockDrift = 60 * time.Second
+
+	// installation tokens are refreshed before they expire.
+	installationTokenRefreshMargin = 5 * time.Minute
+
+	// the lifetime of the cache of installation IDs.
+	installationCacheLifetime = time.Hour
+)
+
+// AppConfig is configure for AppTokenSource.
+type AppConfig struct {
+	// AppID is the ID or the client ID of the GitHub App.
+	AppID string
+
+	// PrivateKey is the PEM encoded private key of the GitHub App.
+	PrivateKey []byte
+
+	// HTTPClient is used for http requests.
+	// If it nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// APIURL is the URL of GitHub API.
+	// If it is empty, the value of GITHUB_API_URL environment value or https://api.github.com is used.
+	APIURL string
+}
+
+// AppTokenSource provides installation access tokens of a GitHub App.
+// The tokens are cached per owner until shortly before they expire.
+type AppTokenSource struct {
+	client *Client
+	appID  string
+	key    sig.SigningKey
+
+	// for testing
+	now func() time.Time
+
+	// the installation IDs by the owner.
+	installations memoize.Group[string, int64]
+
+	// the installation access tokens by the owner.
+	tokens memoize.Group[string, string]
+}
+
+// NewAppTokenSource returns a new AppTokenSource.
+func NewAppTokenSource(cfg *AppConfig) (*AppTokenSource, error) {
+	if cfg.AppID == "" {
+		return nil, errors.New("github: app id is required")
+	}
+	key, _, err := jwk.DecodePEM(cfg.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("github: failed to parse the private key of the app: %w", err)
+	}
+	client, err := NewClientWithConfig(&ClientConfig{
+		HTTPClient: cfg.HTTPClient,
+		APIURL:     cfg.APIURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AppTokenSource{
+		client: client,
+		appID:  cfg.AppID,
+		key:    jwa.RS256.New().NewSigningKey(key),
+		now:    time.Now,
+	}, nil
+}
+
+// Token returns an installation access token that can access the repository.
+func (s *AppTokenSource) Token(ctx context.Context, owner, repo string) (string, error) {
+	token, _, err := s.tokens.Do(ctx, owner, func(ctx context.Context, owner string) (string, time.Time, error) {
+		id, _, err := s.installations.Do(ctx, owner, func(ctx context.Context, owner string) (int64, time.Time, error) {
+			appJWT, err := s.jwt()
+			if err != nil {
+				return 0, time.Time{}, err
+			}
+			resp, err := s.client.GetRepoInstallation(ctx, appJWT, owner, repo)
+			if err != nil {
+				return 0, time.Time{}, err
+			}
+			return resp.ID, s.now().Add(installationCacheLifetime), nil
+		})
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		appJWT, err := s.jwt()
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		resp, err := s.client.CreateInstallationAccessToken(ctx, appJWT, id)
+		if err != nil {
+			var githubErr *UnexpectedStatusCodeError
+			if errors.As(err, &githubErr) && githubErr.StatusCode == http.StatusNotFound {
+				// the app may be reinstalled.
+				s.installations.Forget(owner)
+			}
+			return "", time.Time{}, err
+		}
+		return resp.Token, resp.ExpiresAt.Add(-installationTokenRefreshMargin), nil
+	})
+	return token, err
+}
+
+// jwt returns a new JWT for authenticating as the app.
+func (s *AppTokenSource) jwt() (string, error) {
+	now := s.now()
+	header := jws.NewHeader()
+	header.SetAlgorithm(jwa.RS256)
+	claims := &jwt.Claims{
+		Issuer:         s.appID,
+		IssuedAt:       now.Add(-appJWTClockDrift),
+		ExpirationTime: now.Add(appJWTLifetime),
+	}
+	token, err := jwt.Sign(header, claims, s.key)
+	if err != nil {
+		return "", fmt.Errorf("github: failed to sign the jwt of the app: %w", err)
+	}
+	return string(token), nil
+}
@@ -0,0 +1,169 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shogo82148/goat/jwa"
+	"github.com/shogo82148/goat/jwk"
+	"github.com/shogo82148/goat/jwt"
+)
+
+// fakeGitHubApp is a fake GitHub API server for GitHub Apps.
+type fakeGitHubApp struct {
+	t      *testing.T
+	appID  string
+	key    *jwk.Key
+	now    time.Time
+	server *httptest.Server
+
+	installationCalls atomic.Int64
+	tokenCalls        atomic.Int64
+}
+
+func newFakeGitHubApp(t *testing.T, appID string, pub *rsa.PublicKey, now time.Time) *fakeGitHubApp {
+	key, err := jwk.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &fakeGitHubApp{
+		t:     t,
+		appID: appID,
+		key:   key,
+		now:   now,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/fuller-inc/actions-aws-assume-role/installation", func(w http.ResponseWriter, r *http.Request) {
+		app.installationCalls.Add(1)
+		if !app.verify(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 42, "app_id": 1, "account": {"login": "fuller-inc", "id": 1}}`))
+	})
+	mux.HandleFunc("POST /app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		n := app.tokenCalls.Add(1)
+		if !app.verify(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"token":      "ghs_installationToken" + string(rune('0'+n)),
+			"expires_at": app.now.Add(time.Hour).Format(time.RFC3339),
+		})
+	})
+	app.server = httptest.NewServer(mux)
+	t.Cleanup(app.server.Close)
+	return app
+}
+
+// verify verifies the JWT of the app.
+func (app *fakeGitHubApp) verify(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		app.t.Errorf("unexpected authorization: %q", r.Header.Get("Authorization"))
+		return false
+	}
+	p := &jwt.Parser{
+		AlgorithmVerifier:     jwt.AllowedAlgorithms{jwa.RS256},
+		KeyFinder:             &jwt.JWKKeyFiner{Key: app.key},
+		IssuerSubjectVerifier: jwt.Issuer(app.appID),
+		AudienceVerifier:      jwt.UnsecureAnyAudience,
+	}
+	parsed, err := p.Parse(r.Context(), []byte(token))
+	if err != nil {
+		app.t.Errorf("invalid jwt: %v", err)
+		return false
+	}
+	if exp := parsed.Claims.ExpirationTime.Sub(parsed.Claims.IssuedAt); exp > 10*time.Minute {
+		app.t.Errorf("the lifetime of the jwt is too long: %s", exp)
+	}
+	return true
+}
+
+func generateAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(priv),
+	})
+	return priv, data
+}
+
+func TestAppTokenSource(t *testing.T) {
+	priv, data := generateAppKey(t)
+	now := time.Now()
+	app := newFakeGitHubApp(t, "123456", &priv.PublicKey, now)
+
+	s, err := NewAppTokenSource(&AppConfig{
+		AppID:      "123456",
+		PrivateKey: data,
+		APIURL:     app.server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return now }
+
+	for range 3 {
+		token, err := s.Token(context.Background(), "fuller-inc", "actions-aws-assume-role")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "ghs_installationToken1" {
+			t.Errorf("unexpected token: %q", token)
+		}
+	}
+
+	// the installation and the token are cached.
+	if n := app.installationCalls.Load(); n != 1 {
+		t.Errorf("want 1 installation lookup, got %d", n)
+	}
+	if n := app.tokenCalls.Load(); n != 1 {
+		t.Errorf("want 1 token, got %d", n)
+	}
+}
+
+func TestAppTokenSource_NotInstalled(t *testing.T) {
+	priv, data := generateAppKey(t)
+	app := newFakeGitHubApp(t, "123456", &priv.PublicKey, time.Now())
+
+	s, err := NewAppTokenSource(&AppConfig{
+		AppID:      "123456",
+		PrivateKey: data,
+		APIURL:     app.server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Token(context.Background(), "shogo82148", "actions-aws-assume-role"); err == nil {
+		t.Error("want error, but not")
+	}
+}
+
+func TestNewAppTokenSource_InvalidKey(t *testing.T) {
+	_, err := NewAppTokenSource(&AppConfig{
+		AppID:      "123456",
+		PrivateKey: []byte("not a pem"),
+	})
+	if err == nil {
+		t.Error("want error, but not")
+	}
+}
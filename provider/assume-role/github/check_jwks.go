@@ -0,0 +1,52 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/shogo82148/goat/jwk"
+)
+
+// CheckJWKS checks that the JWK Set for verifying OIDC tokens is available.
+// ParseIDToken caches the JWK Set, but CheckJWKS always fetches it.
+func (c *Client) CheckJWKS(ctx context.Context) error {
+	config, err := c.oidcClient.GetConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("github: failed to get the OpenID Provider configuration: %w", err)
+	}
+
+	// build the request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.JWKSURI, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/jwk-set+json")
+	req.Header.Set("User-Agent", githubUserAgent)
+
+	// send the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// parse the response
+	if err := handleUnexpectedStatusCode(resp); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	set, err := jwk.ParseSet(data)
+	if err != nil {
+		return fmt.Errorf("github: failed to parse JWK Set: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return errors.New("github: JWK Set is empty")
+	}
+	return nil
+}
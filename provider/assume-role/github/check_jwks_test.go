@@ -0,0 +1,62 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckJWKS(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			rw.Write([]byte(`{"issuer":"` + ts.URL + `","jwks_uri":"` + ts.URL + `/.well-known/jwks"}`))
+		case "/.well-known/jwks":
+			rw.Write([]byte(`{"keys":[{"kty":"RSA","alg":"RS256","use":"sig","kid":"dummy",` +
+				`"n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",` +
+				`"e":"AQAB"}]}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewClientWithConfig(&ClientConfig{
+		OIDCIssuer: ts.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckJWKS(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckJWKS_Empty(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			rw.Write([]byte(`{"issuer":"` + ts.URL + `","jwks_uri":"` + ts.URL + `/.well-known/jwks"}`))
+		case "/.well-known/jwks":
+			rw.Write([]byte(`{"keys":[]}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewClientWithConfig(&ClientConfig{
+		OIDCIssuer: ts.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckJWKS(context.Background()); err == nil {
+		t.Error("want error, but not")
+	}
+}
@@ -0,0 +1,104 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type CheckRunStatus string
+
+const (
+	CheckRunStatusQueued     CheckRunStatus = "queued"
+	CheckRunStatusInProgress CheckRunStatus = "in_progress"
+	CheckRunStatusCompleted  CheckRunStatus = "completed"
+)
+
+type CheckRunConclusion string
+
+const (
+	CheckRunConclusionSuccess        CheckRunConclusion = "success"
+	CheckRunConclusionFailure        CheckRunConclusion = "failure"
+	CheckRunConclusionNeutral        CheckRunConclusion = "neutral"
+	CheckRunConclusionCancelled      CheckRunConclusion = "cancelled"
+	CheckRunConclusionSkipped        CheckRunConclusion = "skipped"
+	CheckRunConclusionTimedOut       CheckRunConclusion = "timed_out"
+	CheckRunConclusionActionRequired CheckRunConclusion = "action_required"
+)
+
+type CreateCheckRunRequest struct {
+	Name        string                `json:"name"`
+	HeadSHA     string                `json:"head_sha"`
+	DetailsURL  string                `json:"details_url,omitempty"`
+	ExternalID  string                `json:"external_id,omitempty"`
+	Status      CheckRunStatus        `json:"status,omitempty"`
+	Conclusion  CheckRunConclusion    `json:"conclusion,omitempty"`
+	CompletedAt time.Time             `json:"completed_at,omitzero"`
+	Output      *CreateCheckRunOutput `json:"output,omitempty"`
+}
+
+type CreateCheckRunOutput struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Text    string `json:"text,omitempty"`
+}
+
+type CreateCheckRunResponse struct {
+	ID      int64  `json:"id"`
+	HTMLURL string `json:"html_url"`
+
+	// omit other fields, we don't use them.
+}
+
+// CreateCheckRun creates a check run.
+// The token must be a GitHub App installation token.
+// https://docs.github.com/en/rest/checks/runs#create-a-check-run
+func (c *Client) CreateCheckRun(ctx context.Context, token, owner, repo string, run *CreateCheckRunRequest) (*CreateCheckRunResponse, error) {
+	// validate the parameters
+	if err := validateUserName(owner); err != nil {
+		return nil, err
+	}
+	if err := validateRepoName(repo); err != nil {
+		return nil, err
+	}
+	if err := validateRef(run.HeadSHA); err != nil {
+		return nil, err
+	}
+
+	// build the request
+	u := c.baseURL.JoinPath("repos", url.PathEscape(owner), url.PathEscape(repo), "check-runs")
+	body, err := json.Marshal(run)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", githubUserAgent)
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("X-Github-Api-Version", githubAPIVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	// send the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// parse the response
+	if err := handleUnexpectedStatusCode(resp); err != nil {
+		return nil, err
+	}
+
+	var ret *CreateCheckRunResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,50 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type CreateInstallationAccessTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// omit other fields, we don't use them.
+}
+
+// CreateInstallationAccessToken creates an installation access token of the GitHub App.
+// The token must be a JWT of the app.
+// https://docs.github.com/en/rest/apps/apps#create-an-installation-access-token-for-an-app
+func (c *Client) CreateInstallationAccessToken(ctx context.Context, jwt string, installationID int64) (*CreateInstallationAccessTokenResponse, error) {
+	// build the request
+	u := c.baseURL.JoinPath("app", "installations", strconv.FormatInt(installationID, 10), "access_tokens")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", githubUserAgent)
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("X-Github-Api-Version", githubAPIVersion)
+
+	// send the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// parse the response
+	if err := handleUnexpectedStatusCode(resp); err != nil {
+		return nil, err
+	}
+
+	var ret *CreateInstallationAccessTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,83 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+type ProtectionRuleType string
+
+const (
+	ProtectionRuleTypeRequiredReviewers ProtectionRuleType = "required_reviewers"
+	ProtectionRuleTypeWaitTimer         ProtectionRuleType = "wait_timer"
+	ProtectionRuleTypeBranchPolicy      ProtectionRuleType = "branch_policy"
+)
+
+type GetEnvironmentResponse struct {
+	ID               int64                         `json:"id"`
+	Name             string                        `json:"name"`
+	ProtectionRules  []*GetEnvironmentResponseRule `json:"protection_rules"`
+	DeploymentPolicy *GetEnvironmentResponsePolicy `json:"deployment_branch_policy"`
+
+	// omit other fields, we don't use them.
+}
+
+type GetEnvironmentResponseRule struct {
+	ID                int64              `json:"id"`
+	Type              ProtectionRuleType `json:"type"`
+	WaitTimer         int64              `json:"wait_timer,omitempty"`
+	PreventSelfReview bool               `json:"prevent_self_review,omitempty"`
+
+	// omit other fields, we don't use them.
+}
+
+type GetEnvironmentResponsePolicy struct {
+	ProtectedBranches    bool `json:"protected_branches"`
+	CustomBranchPolicies bool `json:"custom_branch_policies"`
+}
+
+// GetEnvironment gets an environment of the repository.
+// https://docs.github.com/en/rest/deployments/environments#get-an-environment
+func (c *Client) GetEnvironment(ctx context.Context, token, owner, repo, environment string) (*GetEnvironmentResponse, error) {
+	// validate the parameters
+	if err := validateUserName(owner); err != nil {
+		return nil, err
+	}
+	if err := validateRepoName(repo); err != nil {
+		return nil, err
+	}
+	if err := validateEnvironmentName(environment); err != nil {
+		return nil, err
+	}
+
+	// build the request
+	u := c.baseURL.JoinPath("repos", url.PathEscape(owner), url.PathEscape(repo), "environments", url.PathEscape(environment))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", githubUserAgent)
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("X-Github-Api-Version", githubAPIVersion)
+
+	// send the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// parse the response
+	if err := handleUnexpectedStatusCode(resp); err != nil {
+		return nil, err
+	}
+
+	var ret *GetEnvironmentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
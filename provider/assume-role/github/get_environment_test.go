@@ -0,0 +1,96 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestGetEnvironment(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected method: want GET, got %s", r.Method)
+		}
+		path := "/repos/fuller-inc/actions-aws-assume-role/environments/production"
+		if r.URL.Path != path {
+			t.Errorf("unexpected path: want %q, got %q", path, r.URL.Path)
+		}
+
+		data, err := os.ReadFile("testdata/get-environment.json")
+		if err != nil {
+			panic(err)
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		rw.WriteHeader(http.StatusOK)
+		rw.Write(data)
+	}))
+	defer ts.Close()
+	c, err := NewClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = u
+
+	resp, err := c.GetEnvironment(context.Background(), "dummy-auth-token", "fuller-inc", "actions-aws-assume-role", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Name != "production" {
+		t.Errorf("unexpected name: want %q, got %q", "production", resp.Name)
+	}
+	if len(resp.ProtectionRules) != 2 {
+		t.Fatalf("unexpected protection rules: %d", len(resp.ProtectionRules))
+	}
+	if resp.ProtectionRules[1].Type != ProtectionRuleTypeRequiredReviewers {
+		t.Errorf("unexpected type: want %q, got %q", ProtectionRuleTypeRequiredReviewers, resp.ProtectionRules[1].Type)
+	}
+	if !resp.ProtectionRules[1].PreventSelfReview {
+		t.Error("want prevent_self_review, but not")
+	}
+}
+
+func TestGetEnvironment_EscapeName(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		path := "/repos/fuller-inc/actions-aws-assume-role/environments/production%2Fap-northeast-1"
+		if r.URL.EscapedPath() != path {
+			t.Errorf("unexpected path: want %q, got %q", path, r.URL.EscapedPath())
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte(`{"name":"production/ap-northeast-1","protection_rules":[]}`))
+	}))
+	defer ts.Close()
+	c, err := NewClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = u
+
+	if _, err := c.GetEnvironment(context.Background(), "dummy-auth-token", "fuller-inc", "actions-aws-assume-role", "production/ap-northeast-1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetEnvironment_InvalidName(t *testing.T) {
+	c, err := NewClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetEnvironment(context.Background(), "dummy-auth-token", "fuller-inc", "actions-aws-assume-role", "")
+	if err == nil {
+		t.Error("want error, but not")
+	}
+}
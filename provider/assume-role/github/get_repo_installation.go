@@ -0,0 +1,65 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+type GetRepoInstallationResponse struct {
+	ID      int64                               `json:"id"`
+	Account *GetRepoInstallationResponseAccount `json:"account"`
+	AppID   int64                               `json:"app_id"`
+
+	// omit other fields, we don't use them.
+}
+
+type GetRepoInstallationResponseAccount struct {
+	Login string `json:"login"`
+	ID    int64  `json:"id"`
+
+	// omit other fields, we don't use them.
+}
+
+// GetRepoInstallation gets the installation of the GitHub App for the repository.
+// The token must be a JWT of the app.
+// https://docs.github.com/en/rest/apps/apps#get-a-repository-installation-for-the-authenticated-app
+func (c *Client) GetRepoInstallation(ctx context.Context, jwt, owner, repo string) (*GetRepoInstallationResponse, error) {
+	// validate the parameters
+	if err := validateUserName(owner); err != nil {
+		return nil, err
+	}
+	if err := validateRepoName(repo); err != nil {
+		return nil, err
+	}
+
+	// build the request
+	u := c.baseURL.JoinPath("repos", url.PathEscape(owner), url.PathEscape(repo), "installation")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", githubUserAgent)
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("X-Github-Api-Version", githubAPIVersion)
+
+	// send the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// parse the response
+	if err := handleUnexpectedStatusCode(resp); err != nil {
+		return nil, err
+	}
+
+	var ret *GetRepoInstallationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
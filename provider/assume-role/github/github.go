@@ -24,7 +24,8 @@ const (
 	// https://docs.github.com/en/rest/about-the-rest-api/api-versions
 	githubAPIVersion = "2026-03-10"
 
-	oidcIssuer = "https://token.actions.githubusercontent.com"
+	// The default issuer of OIDC tokens
+	defaultOIDCIssuer = "https://token.actions.githubusercontent.com"
 )
 
 var apiBaseURL string
@@ -71,29 +72,66 @@ type Client struct {
 	httpClient *http.Client
 
 	// configure for OpenID Connect
+	oidcIssuer string
 	oidcClient *oidc.Client
 }
 
+// ClientConfig is configure for Client.
+type ClientConfig struct {
+	// HTTPClient is used for http requests.
+	// If it nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// APIURL is the URL of GitHub API.
+	// If it is empty, the value of GITHUB_API_URL environment value or https://api.github.com is used.
+	APIURL string
+
+	// OIDCIssuer is the issuer of OIDC tokens.
+	// If it is empty, https://token.actions.githubusercontent.com is used.
+	// GitHub Enterprise Server uses https://HOSTNAME/_services/token.
+	OIDCIssuer string
+}
+
 func NewClient(httpClient *http.Client) (*Client, error) {
+	return NewClientWithConfig(&ClientConfig{
+		HTTPClient: httpClient,
+	})
+}
+
+func NewClientWithConfig(config *ClientConfig) (*Client, error) {
+	httpClient := config.HTTPClient
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
+	issuer := config.OIDCIssuer
+	if issuer == "" {
+		issuer = defaultOIDCIssuer
+	}
+	baseURL := apiBaseURL
+	if config.APIURL != "" {
+		var err error
+		baseURL, err = canonicalURL(config.APIURL)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	oidcClient, err := oidc.NewClient(&oidc.ClientConfig{
 		Doer:      httpClient,
-		Issuer:    oidcIssuer,
+		Issuer:    issuer,
 		UserAgent: githubUserAgent,
 	})
 	if err != nil {
 		return nil, err
 	}
-	u, err := url.Parse(apiBaseURL)
+	u, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, err
 	}
 	return &Client{
 		baseURL:    u,
 		httpClient: httpClient,
+		oidcIssuer: issuer,
 		oidcClient: oidcClient,
 	}, nil
 }
@@ -209,3 +247,27 @@ func validateRef(s string) error {
 	}
 	return nil
 }
+
+func validateNumber(s string) error {
+	if s == "" {
+		return fmt.Errorf("github: number is empty")
+	}
+	for _, r := range s {
+		if r < '0' || '9' < r {
+			return fmt.Errorf("github: number contains invalid character: %q", r)
+		}
+	}
+	return nil
+}
+
+func validateEnvironmentName(s string) error {
+	if s == "" {
+		return fmt.Errorf("github: environment name is empty")
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("github: environment name contains invalid character: %q", r)
+		}
+	}
+	return nil
+}
@@ -90,3 +90,22 @@ func TestCanonicalURL(t *testing.T) {
 		}
 	}
 }
+
+func TestNewClientWithConfig(t *testing.T) {
+	c, err := NewClientWithConfig(&ClientConfig{
+		APIURL:     "https://GHES.EXAMPLE.COM/api/v3/",
+		OIDCIssuer: "https://ghes.example.com/_services/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ValidateAPIURL("https://ghes.example.com/api/v3"); err != nil {
+		t.Error(err)
+	}
+	if err := c.ValidateAPIURL(defaultAPIBaseURL); err == nil {
+		t.Error("want error, but not")
+	}
+	if c.oidcIssuer != "https://ghes.example.com/_services/token" {
+		t.Errorf("unexpected issuer: %q", c.oidcIssuer)
+	}
+}
@@ -0,0 +1,71 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type Deployment struct {
+	ID          int64     `json:"id"`
+	SHA         string    `json:"sha"`
+	Ref         string    `json:"ref"`
+	Environment string    `json:"environment"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// omit other fields, we don't use them.
+}
+
+// ListDeployments lists the deployments of the commit to the environment.
+// https://docs.github.com/en/rest/deployments/deployments#list-deployments
+func (c *Client) ListDeployments(ctx context.Context, token, owner, repo, sha, environment string) ([]*Deployment, error) {
+	// validate the parameters
+	if err := validateUserName(owner); err != nil {
+		return nil, err
+	}
+	if err := validateRepoName(repo); err != nil {
+		return nil, err
+	}
+	if err := validateRef(sha); err != nil {
+		return nil, err
+	}
+	if err := validateEnvironmentName(environment); err != nil {
+		return nil, err
+	}
+
+	// build the request
+	u := c.baseURL.JoinPath("repos", url.PathEscape(owner), url.PathEscape(repo), "deployments")
+	u.RawQuery = url.Values{
+		"sha":         {sha},
+		"environment": {environment},
+		"per_page":    {"100"},
+	}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", githubUserAgent)
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("X-Github-Api-Version", githubAPIVersion)
+
+	// send the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// parse the response
+	if err := handleUnexpectedStatusCode(resp); err != nil {
+		return nil, err
+	}
+
+	var ret []*Deployment
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,74 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestListDeployments(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected method: want GET, got %s", r.Method)
+		}
+		path := "/repos/fuller-inc/actions-aws-assume-role/deployments"
+		if r.URL.Path != path {
+			t.Errorf("unexpected path: want %q, got %q", path, r.URL.Path)
+		}
+		q := r.URL.Query()
+		if got, want := q.Get("sha"), "e3a45c6c16c1464826b36a598ff39e6cc98c4da4"; got != want {
+			t.Errorf("unexpected sha: want %q, got %q", want, got)
+		}
+		if got, want := q.Get("environment"), "production"; got != want {
+			t.Errorf("unexpected environment: want %q, got %q", want, got)
+		}
+
+		data, err := os.ReadFile("testdata/list-deployments.json")
+		if err != nil {
+			panic(err)
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		rw.WriteHeader(http.StatusOK)
+		rw.Write(data)
+	}))
+	defer ts.Close()
+	c, err := NewClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = u
+
+	resp, err := c.ListDeployments(context.Background(), "dummy-auth-token", "fuller-inc", "actions-aws-assume-role", "e3a45c6c16c1464826b36a598ff39e6cc98c4da4", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) != 1 {
+		t.Fatalf("unexpected deployments: %d", len(resp))
+	}
+	if resp[0].Environment != "production" {
+		t.Errorf("unexpected environment: want %q, got %q", "production", resp[0].Environment)
+	}
+	if resp[0].SHA != "e3a45c6c16c1464826b36a598ff39e6cc98c4da4" {
+		t.Errorf("unexpected sha: got %q", resp[0].SHA)
+	}
+}
+
+func TestListDeployments_InvalidSHA(t *testing.T) {
+	c, err := NewClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.ListDeployments(context.Background(), "dummy-auth-token", "fuller-inc", "actions-aws-assume-role", "main&environment=staging", "production")
+	if err == nil {
+		t.Error("want error, but not")
+	}
+}
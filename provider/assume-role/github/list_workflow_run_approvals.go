@@ -0,0 +1,81 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+type ApprovalState string
+
+const (
+	ApprovalStateApproved ApprovalState = "approved"
+	ApprovalStateRejected ApprovalState = "rejected"
+	ApprovalStatePending  ApprovalState = "pending"
+)
+
+type WorkflowRunApproval struct {
+	State        ApprovalState                     `json:"state"`
+	Comment      string                            `json:"comment"`
+	Environments []*WorkflowRunApprovalEnvironment `json:"environments"`
+	User         *WorkflowRunApprovalUser          `json:"user"`
+}
+
+type WorkflowRunApprovalEnvironment struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+
+	// omit other fields, we don't use them.
+}
+
+type WorkflowRunApprovalUser struct {
+	Login string `json:"login"`
+	ID    int64  `json:"id"`
+
+	// omit other fields, we don't use them.
+}
+
+// ListWorkflowRunApprovals gets the review history of the deployments in the workflow run.
+// https://docs.github.com/en/rest/actions/workflow-runs#get-the-review-history-for-a-workflow-run
+func (c *Client) ListWorkflowRunApprovals(ctx context.Context, token, owner, repo, runID string) ([]*WorkflowRunApproval, error) {
+	// validate the parameters
+	if err := validateUserName(owner); err != nil {
+		return nil, err
+	}
+	if err := validateRepoName(repo); err != nil {
+		return nil, err
+	}
+	if err := validateNumber(runID); err != nil {
+		return nil, err
+	}
+
+	// build the request
+	u := c.baseURL.JoinPath("repos", url.PathEscape(owner), url.PathEscape(repo), "actions", "runs", runID, "approvals")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", githubUserAgent)
+	req.Header.Set("Authorization", "token "+token)
+	req.Header.Set("X-Github-Api-Version", githubAPIVersion)
+
+	// send the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// parse the response
+	if err := handleUnexpectedStatusCode(resp); err != nil {
+		return nil, err
+	}
+
+	var ret []*WorkflowRunApproval
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,59 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestListWorkflowRunApprovals(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected method: want GET, got %s", r.Method)
+		}
+		path := "/repos/fuller-inc/actions-aws-assume-role/actions/runs/1234567890/approvals"
+		if r.URL.Path != path {
+			t.Errorf("unexpected path: want %q, got %q", path, r.URL.Path)
+		}
+
+		data, err := os.ReadFile("testdata/list-workflow-run-approvals.json")
+		if err != nil {
+			panic(err)
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		rw.WriteHeader(http.StatusOK)
+		rw.Write(data)
+	}))
+	defer ts.Close()
+	c, err := NewClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.baseURL = u
+
+	resp, err := c.ListWorkflowRunApprovals(context.Background(), "dummy-auth-token", "fuller-inc", "actions-aws-assume-role", "1234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) != 1 {
+		t.Fatalf("unexpected approvals: %d", len(resp))
+	}
+	if resp[0].State != ApprovalStateApproved {
+		t.Errorf("unexpected state: want %q, got %q", ApprovalStateApproved, resp[0].State)
+	}
+	if resp[0].Environments[0].Name != "production" {
+		t.Errorf("unexpected environment: want %q, got %q", "production", resp[0].Environments[0].Name)
+	}
+	if resp[0].User.Login != "shogo82148" {
+		t.Errorf("unexpected user: want %q, got %q", "shogo82148", resp[0].User.Login)
+	}
+}
@@ -54,7 +54,7 @@ func (c *Client) ParseIDToken(ctx context.Context, idToken string) (*ActionsIDTo
 			return
 		}),
 		AlgorithmVerifier:     jwt.AllowedAlgorithms{jwa.RS256},
-		IssuerSubjectVerifier: jwt.Issuer(oidcIssuer),
+		IssuerSubjectVerifier: jwt.Issuer(c.oidcIssuer),
 		AudienceVerifier:      jwt.UnsecureAnyAudience,
 	}
 	token, err := p.Parse(ctx, []byte(idToken))
@@ -30,7 +30,7 @@ func TestParseIDToken_Integrated(t *testing.T) {
 
 	oidcClient, err := oidc.NewClient(&oidc.ClientConfig{
 		Doer:   http.DefaultClient,
-		Issuer: oidcIssuer,
+		Issuer: defaultOIDCIssuer,
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -43,6 +43,7 @@ func TestParseIDToken_Integrated(t *testing.T) {
 	c := &Client{
 		baseURL:    u,
 		httpClient: http.DefaultClient,
+		oidcIssuer: defaultOIDCIssuer,
 		oidcClient: oidcClient,
 	}
 	id, err := c.ParseIDToken(ctx, token)
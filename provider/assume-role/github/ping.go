@@ -0,0 +1,36 @@
+package github
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Ping checks that the GitHub API is reachable.
+// It calls the rate limit API, because it doesn't count against the rate limit.
+// https://docs.github.com/en/rest/rate-limit/rate-limit#get-rate-limit-status-for-the-authenticated-user
+func (c *Client) Ping(ctx context.Context) error {
+	// build the request
+	u := c.baseURL.JoinPath("rate_limit")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", githubUserAgent)
+	req.Header.Set("X-Github-Api-Version", githubAPIVersion)
+
+	// send the request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// parse the response
+	if err := handleUnexpectedStatusCode(resp); err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
@@ -0,0 +1,86 @@
+package assumerole
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// the instructions for migrating from GITHUB_TOKEN to OIDC.
+const githubTokenMigrationGuide = "Add `id-token: write` to the permissions of the job to use OIDC. " +
+	"See https://github.com/fuller-inc/actions-aws-assume-role/issues/454 and https://docs.github.com/en/actions/deployment/security-hardening-your-deployments/about-security-hardening-with-openid-connect ."
+
+// GitHubTokenAuth restricts the deprecated authentication with GITHUB_TOKEN.
+// If it is not configured, all repositories can authenticate with GITHUB_TOKEN.
+type GitHubTokenAuth struct {
+	// Disabled rejects all requests without OIDC tokens.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Owners is a list of patterns of owners that can still use GITHUB_TOKEN, e.g. "fuller-inc".
+	// If it is empty, all owners can.
+	Owners []string `json:"owners,omitempty"`
+
+	// Sunset is the time when GITHUB_TOKEN is disabled.
+	// If it is zero, GITHUB_TOKEN is never disabled by time.
+	Sunset time.Time `json:"sunset,omitzero"`
+}
+
+// githubTokenAllowed reports whether any request can authenticate with GITHUB_TOKEN now.
+func (h *Handler) githubTokenAllowed() bool {
+	auth := h.githubTokenAuth
+	if auth == nil {
+		return true
+	}
+	return !auth.Disabled && (auth.Sunset.IsZero() || h.now().Before(auth.Sunset))
+}
+
+// checkGitHubTokenAuth checks whether the repository can authenticate with GITHUB_TOKEN.
+// It returns a warning about the sunset if it is allowed.
+func (h *Handler) checkGitHubTokenAuth(ctx context.Context, req *requestBody) (string, error) {
+	auth := h.githubTokenAuth
+	if auth == nil {
+		return "", nil
+	}
+
+	var reason, code string
+	owner, _, _ := strings.Cut(req.Repository, "/")
+	switch {
+	case auth.Disabled:
+		reason = "authentication with GITHUB_TOKEN is disabled on this credential provider."
+		code = "GitHubTokenDisabled"
+	case !auth.Sunset.IsZero() && !h.now().Before(auth.Sunset):
+		reason = fmt.Sprintf("authentication with GITHUB_TOKEN was disabled on %s.", auth.Sunset.UTC().Format(time.RFC3339))
+		code = "GitHubTokenSunset"
+	case len(auth.Owners) > 0 && !slices.ContainsFunc(auth.Owners, func(pattern string) bool {
+		return matchPattern(strings.ToLower(pattern), strings.ToLower(owner))
+	}):
+		reason = fmt.Sprintf("%s is not allowed to authenticate with GITHUB_TOKEN.", owner)
+		code = "GitHubTokenNotAllowed"
+	}
+	if code != "" {
+		// the owner is claimed by the request and not verified yet.
+		// it is logged instead of the label of the metrics, so that forged requests don't make the labels unbounded.
+		h.log().InfoContext(ctx, "rejected GITHUB_TOKEN",
+			slog.String("repository", req.Repository),
+			slog.String("owner", owner),
+			slog.Bool("verified", false),
+			slog.String("reason", code),
+		)
+		h.metrics.observeGitHubToken("", "rejected")
+		return "", &validationError{
+			message: reason + " " + githubTokenMigrationGuide,
+			code:    code,
+			status:  http.StatusForbidden,
+		}
+	}
+
+	if auth.Sunset.IsZero() {
+		return "", nil
+	}
+	return fmt.Sprintf("Authentication with GITHUB_TOKEN will be disabled on %s. %s\n",
+		auth.Sunset.UTC().Format(time.RFC3339), githubTokenMigrationGuide), nil
+}
@@ -0,0 +1,170 @@
+package assumerole
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/metrics"
+)
+
+const dummyGitHubTokenRequest = `{
+	"github_token": "ghs_dummyGitHubToken",
+	"role_to_assume": "arn:aws:iam::123456789012:role/assume-role-test",
+	"role_session_name": "GitHubActions",
+	"duration_seconds": 900,
+	"repository": "fuller-inc/actions-aws-assume-role",
+	"sha": "e3a45c6c16c1464826b36a598ff39e6cc98c4da4",
+	"run_id": "1234567890",
+	"workflow": "test",
+	"actor": "fuller-inc"
+}`
+
+func TestCheckGitHubTokenAuth(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		auth    *GitHubTokenAuth
+		code    string
+		warning bool
+	}{
+		{
+			name: "not configured",
+			auth: nil,
+		},
+		{
+			name: "disabled",
+			auth: &GitHubTokenAuth{Disabled: true},
+			code: "GitHubTokenDisabled",
+		},
+		{
+			name: "allowed owner",
+			auth: &GitHubTokenAuth{Owners: []string{"shogo82148", "Fuller-*"}},
+		},
+		{
+			name: "not allowed owner",
+			auth: &GitHubTokenAuth{Owners: []string{"shogo82148"}},
+			code: "GitHubTokenNotAllowed",
+		},
+		{
+			name:    "before the sunset",
+			auth:    &GitHubTokenAuth{Sunset: now.Add(time.Hour)},
+			warning: true,
+		},
+		{
+			name: "after the sunset",
+			auth: &GitHubTokenAuth{Sunset: now},
+			code: "GitHubTokenSunset",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{
+				clock:           func() time.Time { return now },
+				githubTokenAuth: tt.auth,
+			}
+			warning, err := h.checkGitHubTokenAuth(context.Background(), &requestBody{
+				Repository: "fuller-inc/actions-aws-assume-role",
+			})
+			if tt.code != "" {
+				var validation *validationError
+				if !errors.As(err, &validation) || validation.code != tt.code {
+					t.Fatalf("want %s, got %v", tt.code, err)
+				}
+				if validation.status != http.StatusForbidden {
+					t.Errorf("unexpected status: %d", validation.status)
+				}
+				if !strings.Contains(validation.message, "id-token: write") {
+					t.Errorf("the message doesn't have the instructions: %q", validation.message)
+				}
+				if h.githubTokenAllowed() == (tt.code != "GitHubTokenNotAllowed") {
+					t.Errorf("unexpected githubTokenAllowed: %v", h.githubTokenAllowed())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (warning != "") != tt.warning {
+				t.Errorf("unexpected warning: %q", warning)
+			}
+		})
+	}
+}
+
+func TestGitHubTokenAuth(t *testing.T) {
+	registry := metrics.NewRegistry()
+	var logs bytes.Buffer
+	serve := func(auth *GitHubTokenAuth) *httptest.ResponseRecorder {
+		t.Helper()
+		h, err := NewHandlerWithOptions(
+			WithGitHubClient(&githubClientDummy{}),
+			WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+				return &stsClientDummy{}, nil
+			}),
+			WithMetrics(registry),
+			WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+			WithGitHubTokenAuth(auth),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(dummyGitHubTokenRequest))
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(&GitHubTokenAuth{Owners: []string{"fuller-inc"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(&GitHubTokenAuth{Disabled: true})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp errorResponseBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp.Message, "authentication with GITHUB_TOKEN is disabled") {
+		t.Errorf("unexpected message: %q", resp.Message)
+	}
+
+	// the owner of rejected requests is logged so that they can be aggregated.
+	if want := `msg="rejected GITHUB_TOKEN" repository=fuller-inc/actions-aws-assume-role owner=fuller-inc verified=false reason=GitHubTokenDisabled`; !strings.Contains(logs.String(), want) {
+		t.Errorf("%q is not found in:\n%s", want, logs.String())
+	}
+
+	var buf bytes.Buffer
+	if err := registry.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`assume_role_github_token_requests_total{owner="fuller-inc",result="accepted"} 1`,
+		`assume_role_github_token_requests_total{owner="",result="rejected"} 1`,
+		`assume_role_requests_total{endpoint="assume_role",outcome="client_error",error_code="GitHubTokenDisabled"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%q is not found in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestCapabilities_GitHubTokenDisabled(t *testing.T) {
+	h := &Handler{
+		githubTokenAuth: &GitHubTokenAuth{Disabled: true},
+	}
+	if got := h.capabilities().CredentialTypes; slices.Contains(got, "github_token") {
+		t.Errorf("unexpected credential types: %q", got)
+	}
+}
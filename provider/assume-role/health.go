@@ -0,0 +1,157 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const (
+	// the results of health checks are cached for this duration,
+	// so that monitoring systems can't cause too many requests to GitHub and AWS.
+	healthCheckCacheTTL = 30 * time.Second
+
+	// the timeout of each health check.
+	healthCheckTimeout = 5 * time.Second
+)
+
+const (
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+)
+
+// healthCheckResult is the result of a health check.
+// The endpoints are not authenticated, so it doesn't have the errors or the identity of the provider;
+// they are written to the logs.
+type healthCheckResult struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	LatencyMS int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type healthReport struct {
+	Status string               `json:"status"`
+	Checks []*healthCheckResult `json:"checks"`
+}
+
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (h *Handler) healthChecks() []healthCheck {
+	return []healthCheck{
+		{
+			name:  "jwks",
+			check: h.github.CheckJWKS,
+		},
+		{
+			name:  "github",
+			check: h.github.Ping,
+		},
+		{
+			name: "sts",
+			check: func(ctx context.Context) error {
+				resp, err := h.sts.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+				if err != nil {
+					return err
+				}
+				h.log().DebugContext(ctx, "sts caller identity",
+					slog.String("account", aws.ToString(resp.Account)),
+					slog.String("arn", aws.ToString(resp.Arn)),
+				)
+				return nil
+			},
+		},
+	}
+}
+
+// checkHealth runs the health checks concurrently.
+// The results are cached for healthCheckCacheTTL.
+func (h *Handler) checkHealth(ctx context.Context) *healthReport {
+	checks := h.healthChecks()
+	results := make([]*healthCheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Go(func() {
+			result, _, err := h.healthCache.Do(ctx, c.name, func(ctx context.Context, name string) (*healthCheckResult, time.Time, error) {
+				return h.runHealthCheck(ctx, c), h.now().Add(healthCheckCacheTTL), nil
+			})
+			if err != nil {
+				// the context is canceled.
+				h.log().WarnContext(ctx, "health check failed", slog.String("name", c.name), slog.String("error", err.Error()))
+				result = &healthCheckResult{
+					Name:      c.name,
+					Status:    healthStatusFail,
+					CheckedAt: h.now(),
+				}
+			}
+			results[i] = result
+		})
+	}
+	wg.Wait()
+
+	report := &healthReport{
+		Status: healthStatusOK,
+		Checks: results,
+	}
+	for _, result := range results {
+		if result.Status != healthStatusOK {
+			report.Status = healthStatusFail
+		}
+	}
+	return report
+}
+
+func (h *Handler) runHealthCheck(ctx context.Context, c healthCheck) *healthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := h.now()
+	err := c.check(ctx)
+	result := &healthCheckResult{
+		Name:      c.name,
+		Status:    healthStatusOK,
+		LatencyMS: h.now().Sub(start).Milliseconds(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		h.log().WarnContext(ctx, "health check failed", slog.String("name", c.name), slog.String("error", err.Error()))
+		result.Status = healthStatusFail
+	}
+	return result
+}
+
+// serveHealthz reports the diagnostic report.
+// It always returns 200 OK while the process is alive, even if some checks fail.
+func (h *Handler) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	h.writeHealthReport(w, r, h.checkHealth(r.Context()), http.StatusOK)
+}
+
+// serveReadyz reports the diagnostic report.
+// It returns 503 Service Unavailable if some checks fail.
+func (h *Handler) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	report := h.checkHealth(r.Context())
+	status := http.StatusOK
+	if report.Status != healthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	h.writeHealthReport(w, r, report, status)
+}
+
+func (h *Handler) writeHealthReport(w http.ResponseWriter, r *http.Request, report *healthReport, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		h.log().InfoContext(r.Context(), "failed to write the response", slog.String("error", err.Error()))
+	}
+}
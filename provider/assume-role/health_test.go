@@ -0,0 +1,114 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+func TestReadyz(t *testing.T) {
+	h := &Handler{
+		github: &githubClientMock{
+			CheckJWKSFunc: func(ctx context.Context) error {
+				return nil
+			},
+			PingFunc: func(ctx context.Context) error {
+				return nil
+			},
+		},
+		sts: &stsClientMock{
+			GetCallerIdentityFunc: func(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+				return &sts.GetCallerIdentityOutput{
+					Account: aws.String("123456789012"),
+					Arn:     aws.String("arn:aws:sts::123456789012:assumed-role/provider/session"),
+				}, nil
+			},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status code: want %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var report healthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != healthStatusOK {
+		t.Errorf("unexpected status: want %q, got %q", healthStatusOK, report.Status)
+	}
+	if len(report.Checks) != 3 {
+		t.Fatalf("unexpected checks: %d", len(report.Checks))
+	}
+
+	// the identity of the provider is not exposed.
+	if strings.Contains(w.Body.String(), "123456789012") {
+		t.Errorf("the response has the account id: %s", w.Body.String())
+	}
+}
+
+func TestReadyz_Fail(t *testing.T) {
+	var count atomic.Int64
+	h := &Handler{
+		github: &githubClientMock{
+			CheckJWKSFunc: func(ctx context.Context) error {
+				return nil
+			},
+			PingFunc: func(ctx context.Context) error {
+				count.Add(1)
+				return errors.New("github is down")
+			},
+		},
+		sts: &stsClientMock{
+			GetCallerIdentityFunc: func(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+				return &sts.GetCallerIdentityOutput{}, nil
+			},
+		},
+	}
+
+	for range 3 {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("unexpected status code: want %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+	}
+
+	// the failures are also cached.
+	if count.Load() != 1 {
+		t.Errorf("want 1 call, got %d", count.Load())
+	}
+
+	// /healthz reports the failure, but the status code is 200 OK.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status code: want %d, got %d", http.StatusOK, w.Code)
+	}
+	var report healthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != healthStatusFail {
+		t.Errorf("unexpected status: want %q, got %q", healthStatusFail, report.Status)
+	}
+	if report.Checks[1].Status != healthStatusFail {
+		t.Errorf("unexpected status: %q", report.Checks[1].Status)
+	}
+	if strings.Contains(w.Body.String(), "github is down") {
+		t.Errorf("the response has the error: %s", w.Body.String())
+	}
+}
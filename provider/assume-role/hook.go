@@ -0,0 +1,102 @@
+package assumerole
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+// Hook is called around issuing credentials.
+// It is useful for plugging custom logic into issuance,
+// e.g. checking a change-freeze calendar, or notifying a chat.
+type Hook interface {
+	// BeforeIssue is called before assuming the role.
+	// If it returns an error, the credentials are not issued.
+	// Return *VetoError to reject the request with a custom message.
+	BeforeIssue(ctx context.Context, issuance *Issuance) error
+
+	// AfterIssue is called after the role is assumed, whether it succeeded or not.
+	AfterIssue(ctx context.Context, issuance *Issuance, result *IssueResult)
+}
+
+// Issuance describes the credentials to be issued.
+type Issuance struct {
+	// IDToken is the verified claims of the OIDC token.
+	// It is nil if the request is authenticated by GITHUB_TOKEN.
+	IDToken *github.ActionsIDToken
+
+	// Repository is the full name of the repository, e.g. "fuller-inc/actions-aws-assume-role".
+	Repository string
+
+	// RoleARN is the ARN of the role to assume.
+	RoleARN string
+
+	// RoleSessionName is the name of the role session.
+	RoleSessionName string
+
+	// DurationSeconds is the duration of the role session.
+	DurationSeconds int32
+
+	mu       sync.Mutex
+	warnings []string
+}
+
+// Warn adds a warning message to the response.
+// The message is shown in the log of the workflow.
+func (i *Issuance) Warn(message string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.warnings = append(i.warnings, message)
+}
+
+// warning returns the warning messages joined by newlines.
+func (i *Issuance) warning() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	var s string
+	for _, w := range i.warnings {
+		s += w + "\n"
+	}
+	return s
+}
+
+// IssueResult is the result of issuing credentials.
+type IssueResult struct {
+	// Err is the error of assuming the role. It is nil if the credentials are issued.
+	Err error
+
+	// Expiration is the time when the credentials expire.
+	Expiration time.Time
+}
+
+// VetoError is an error that hooks return to reject the request.
+// The message is shown to the user.
+type VetoError struct {
+	Message string
+}
+
+func (err *VetoError) Error() string {
+	return err.Message
+}
+
+func (h *Handler) beforeIssue(ctx context.Context, issuance *Issuance) error {
+	for _, hook := range h.hooks {
+		if err := hook.BeforeIssue(ctx, issuance); err != nil {
+			var veto *VetoError
+			if errors.As(err, &veto) {
+				h.log().InfoContext(ctx, "the request is vetoed by the hook")
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Handler) afterIssue(ctx context.Context, issuance *Issuance, result *IssueResult) {
+	for _, hook := range h.hooks {
+		hook.AfterIssue(ctx, issuance, result)
+	}
+}
@@ -0,0 +1,111 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+type hookFunc struct {
+	before func(ctx context.Context, issuance *Issuance) error
+	after  func(ctx context.Context, issuance *Issuance, result *IssueResult)
+}
+
+func (h *hookFunc) BeforeIssue(ctx context.Context, issuance *Issuance) error {
+	if h.before == nil {
+		return nil
+	}
+	return h.before(ctx, issuance)
+}
+
+func (h *hookFunc) AfterIssue(ctx context.Context, issuance *Issuance, result *IssueResult) {
+	if h.after != nil {
+		h.after(ctx, issuance, result)
+	}
+}
+
+func TestHook_Veto(t *testing.T) {
+	h := NewDummyHandler()
+	h.hooks = []Hook{
+		&hookFunc{
+			before: func(ctx context.Context, issuance *Issuance) error {
+				if issuance.IDToken == nil || issuance.IDToken.Ref != "refs/heads/main" {
+					t.Errorf("unexpected claims: %#v", issuance.IDToken)
+				}
+				return &VetoError{Message: "production is frozen until Monday"}
+			},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(dummyAssumeRoleRequest))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("unexpected status code: want %d, got %d", http.StatusForbidden, w.Code)
+	}
+	var body errorResponseBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Message != "production is frozen until Monday" {
+		t.Errorf("unexpected message: %q", body.Message)
+	}
+}
+
+func TestHook_Warning(t *testing.T) {
+	h := NewDummyHandler()
+	h.hooks = []Hook{
+		&hookFunc{
+			before: func(ctx context.Context, issuance *Issuance) error {
+				issuance.Warn("the change freeze starts tomorrow")
+				return nil
+			},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(dummyAssumeRoleRequest))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", w.Code)
+	}
+	var resp responseBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Warning, "the change freeze starts tomorrow\n") {
+		t.Errorf("unexpected warning: %q", resp.Warning)
+	}
+}
+
+func TestHook_AfterIssueFailure(t *testing.T) {
+	h := NewDummyHandler()
+	h.sts = &stsClientMock{
+		AssumeRoleFunc: func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+			return nil, errAccessDenied
+		},
+	}
+	var result *IssueResult
+	h.hooks = []Hook{
+		&hookFunc{
+			after: func(ctx context.Context, issuance *Issuance, r *IssueResult) {
+				result = r
+			},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(dummyAssumeRoleRequest))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status code: %d", w.Code)
+	}
+	if result == nil || result.Err == nil {
+		t.Errorf("AfterIssue should be called with the error: %#v", result)
+	}
+}
@@ -0,0 +1,46 @@
+package assumerole
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+// bindIDToken derives the identity of the request from the OIDC token.
+// The fields of the environment values are optional with OIDC tokens,
+// but they must match the claims if they are supplied.
+// After that, req has the values of the claims, so that nothing trusts the values supplied by the client.
+func bindIDToken(req *requestBody, idToken *github.ActionsIDToken) error {
+	fields := []struct {
+		name  string
+		value *string
+		claim string
+
+		// the names of repositories and users are case-insensitive.
+		fold bool
+	}{
+		{"repository", &req.Repository, idToken.Repository, true},
+		{"sha", &req.SHA, idToken.SHA, false},
+		{"run_id", &req.RunID, idToken.RunID, false},
+		{"workflow", &req.Workflow, idToken.Workflow, false},
+		{"actor", &req.Actor, idToken.Actor, true},
+		{"branch", &req.Branch, idToken.Ref, false},
+	}
+	for _, f := range fields {
+		if *f.value == "" {
+			continue
+		}
+		if *f.value == f.claim || (f.fold && strings.EqualFold(*f.value, f.claim)) {
+			continue
+		}
+		return &validationError{
+			message: fmt.Sprintf("%s %q doesn't match the OIDC token %q", f.name, *f.value, f.claim),
+			code:    "IdentityMismatch",
+		}
+	}
+	for _, f := range fields {
+		*f.value = f.claim
+	}
+	return nil
+}
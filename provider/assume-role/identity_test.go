@@ -0,0 +1,149 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+func TestBindIDToken(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *requestBody
+		ok   bool
+	}{
+		{
+			name: "without environment values",
+			req:  &requestBody{},
+			ok:   true,
+		},
+		{
+			name: "same values",
+			req: &requestBody{
+				Repository: "fuller-inc/actions-aws-assume-role",
+				SHA:        "e3a45c6c16c1464826b36a598ff39e6cc98c4da4",
+				RunID:      "1234567890",
+				Workflow:   "test",
+				Actor:      "shogo82148",
+				Branch:     "refs/heads/main",
+			},
+			ok: true,
+		},
+		{
+			name: "names are case-insensitive",
+			req: &requestBody{
+				Repository: "Fuller-Inc/Actions-AWS-Assume-Role",
+				Actor:      "Shogo82148",
+			},
+			ok: true,
+		},
+		{
+			name: "another repository",
+			req: &requestBody{
+				Repository: "fuller-inc/another-repository",
+			},
+			ok: false,
+		},
+		{
+			name: "another commit",
+			req: &requestBody{
+				SHA: "0000000000000000000000000000000000000000",
+			},
+			ok: false,
+		},
+		{
+			name: "another branch",
+			req: &requestBody{
+				Branch: "refs/heads/production",
+			},
+			ok: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idToken := dummyIDToken()
+			err := bindIDToken(tt.req, idToken)
+			if !tt.ok {
+				var validation *validationError
+				if !errors.As(err, &validation) || validation.code != "IdentityMismatch" {
+					t.Errorf("want IdentityMismatch, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if !boundToIDToken(tt.req, idToken) {
+				t.Errorf("unexpected request: %#v", tt.req)
+			}
+		})
+	}
+}
+
+// boundToIDToken reports whether the request has the values of the claims.
+func boundToIDToken(req *requestBody, idToken *github.ActionsIDToken) bool {
+	return req.Repository == idToken.Repository && req.SHA == idToken.SHA &&
+		req.RunID == idToken.RunID && req.Workflow == idToken.Workflow &&
+		req.Actor == idToken.Actor && req.Branch == idToken.Ref
+}
+
+func TestAssumeRole_OIDCOnly(t *testing.T) {
+	h, err := NewHandlerWithOptions(
+		WithGitHubClient(&githubClientDummy{}),
+		WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+			return &stsClientDummy{}, nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the environment values are optional with OIDC tokens.
+	body := `{
+		"id_token": "dummyGitHubIDToken",
+		"role_to_assume": "arn:aws:iam::123456789012:role/assume-role-test",
+		"role_session_name": "GitHubActions",
+		"duration_seconds": 900,
+		"role_session_tagging": true
+	}`
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	// both v1 and v2 require the environment values to match the claims.
+	body = strings.Replace(dummyAssumeRoleRequest, `"repository": "fuller-inc/actions-aws-assume-role"`, `"repository": "fuller-inc/another-repository"`, 1)
+	for _, path := range []string{"/", "/assume-role", "/v2/assume-role"} {
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: unexpected status code: %d, body: %s", path, w.Code, w.Body.String())
+		}
+		var resp errorResponseBody
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(resp.Message, "doesn't match the OIDC token") {
+			t.Errorf("%s: unexpected message: %q", path, resp.Message)
+		}
+	}
+}
+
+func TestValidate_GitHubTokenRequiresEnvironmentValues(t *testing.T) {
+	h := &Handler{github: &githubClientDummy{}}
+	err := h.validate(context.Background(), &requestBody{
+		GitHubToken:     "ghs_dummyGitHubToken",
+		RoleToAssume:    "arn:aws:iam::123456789012:role/assume-role-test",
+		RoleSessionName: "GitHubActions",
+		DurationSeconds: 900,
+	})
+	if err == nil || !strings.Contains(err.Error(), "GITHUB_REPOSITORY") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
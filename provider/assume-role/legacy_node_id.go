@@ -0,0 +1,232 @@
+package assumerole
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// the default maximum number of the usages that MemoryLegacyNodeIDStore remembers.
+const defaultMaxLegacyNodeIDUsages = 10000
+
+// LegacyNodeIDUsage is a pair of a repository and a role
+// that is authorized only by the legacy node IDs.
+type LegacyNodeIDUsage struct {
+	Repository string `json:"repository"`
+	RoleARN    string `json:"role_arn"`
+
+	// LegacyNodeID and NextNodeID are the node IDs of the repository in each format.
+	// NextNodeID is empty if the lookup of the next format failed.
+	LegacyNodeID string `json:"legacy_node_id"`
+	NextNodeID   string `json:"next_node_id,omitempty"`
+
+	// TrustPolicyDiff is the changes of the conditions of the trust policy
+	// that are needed for migrating to the next format.
+	TrustPolicyDiff []*TrustPolicyChange `json:"trust_policy_diff"`
+
+	// Count is the number of the requests.
+	Count int64 `json:"count"`
+
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// TrustPolicyChange is a change of a condition of the trust policy.
+type TrustPolicyChange struct {
+	// ConditionKey is the key of the condition, e.g. "sts:ExternalId" and "aws:RequestTag/Repository".
+	ConditionKey string `json:"condition_key"`
+
+	Legacy string `json:"legacy"`
+	Next   string `json:"next"`
+}
+
+// LegacyNodeIDStore records the usages of the legacy node IDs.
+type LegacyNodeIDStore interface {
+	// Record records a request.
+	// The usages of the same repository and role are merged.
+	Record(ctx context.Context, usage *LegacyNodeIDUsage) error
+
+	// List returns all usages sorted by the repository and the role.
+	List(ctx context.Context) ([]*LegacyNodeIDUsage, error)
+}
+
+// MemoryLegacyNodeIDStore stores the usages in memory of the process.
+// If it is full, the least recently seen usage is forgotten.
+type MemoryLegacyNodeIDStore struct {
+	max int
+
+	mu     sync.Mutex
+	usages map[legacyNodeIDKey]*LegacyNodeIDUsage
+}
+
+var _ LegacyNodeIDStore = (*MemoryLegacyNodeIDStore)(nil)
+
+type legacyNodeIDKey struct {
+	repository string
+	roleARN    string
+}
+
+// NewMemoryLegacyNodeIDStore returns a new MemoryLegacyNodeIDStore.
+func NewMemoryLegacyNodeIDStore() *MemoryLegacyNodeIDStore {
+	return &MemoryLegacyNodeIDStore{
+		max:    defaultMaxLegacyNodeIDUsages,
+		usages: make(map[legacyNodeIDKey]*LegacyNodeIDUsage),
+	}
+}
+
+// Record implements LegacyNodeIDStore.
+func (s *MemoryLegacyNodeIDStore) Record(ctx context.Context, usage *LegacyNodeIDUsage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := legacyNodeIDKey{repository: usage.Repository, roleARN: usage.RoleARN}
+	old, ok := s.usages[key]
+	if !ok && len(s.usages) >= s.max {
+		s.evict()
+	}
+
+	u := *usage
+	u.TrustPolicyDiff = slices.Clone(usage.TrustPolicyDiff)
+	if ok {
+		u.Count += old.Count
+		if !old.FirstSeen.IsZero() && old.FirstSeen.Before(u.FirstSeen) {
+			u.FirstSeen = old.FirstSeen
+		}
+		if old.LastSeen.After(u.LastSeen) {
+			u.LastSeen = old.LastSeen
+		}
+	}
+	s.usages[key] = &u
+	return nil
+}
+
+// evict forgets the least recently seen usage.
+func (s *MemoryLegacyNodeIDStore) evict() {
+	var oldest legacyNodeIDKey
+	var lastSeen time.Time
+	first := true
+	for k, u := range s.usages {
+		if first || u.LastSeen.Before(lastSeen) {
+			oldest, lastSeen, first = k, u.LastSeen, false
+		}
+	}
+	delete(s.usages, oldest)
+}
+
+// List implements LegacyNodeIDStore.
+func (s *MemoryLegacyNodeIDStore) List(ctx context.Context) ([]*LegacyNodeIDUsage, error) {
+	s.mu.Lock()
+	ret := make([]*LegacyNodeIDUsage, 0, len(s.usages))
+	for _, u := range s.usages {
+		v := *u
+		v.TrustPolicyDiff = slices.Clone(u.TrustPolicyDiff)
+		ret = append(ret, &v)
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(ret, func(a, b *LegacyNodeIDUsage) int {
+		return cmp.Or(
+			cmp.Compare(a.Repository, b.Repository),
+			cmp.Compare(a.RoleARN, b.RoleARN),
+		)
+	})
+	return ret, nil
+}
+
+// recordLegacyNodeID records that the role is assumed only with the legacy node IDs.
+// next is nil if the lookup of the next format failed.
+func (h *Handler) recordLegacyNodeID(ctx context.Context, req *requestBody, legacy, next *sts.AssumeRoleInput) {
+	now := h.now()
+	usage := &LegacyNodeIDUsage{
+		Repository:   req.Repository,
+		RoleARN:      req.RoleToAssume,
+		LegacyNodeID: aws.ToString(legacy.ExternalId),
+		Count:        1,
+		FirstSeen:    now,
+		LastSeen:     now,
+	}
+	if next != nil {
+		usage.NextNodeID = aws.ToString(next.ExternalId)
+		usage.TrustPolicyDiff = trustPolicyDiff(legacy, next)
+	}
+	h.log().InfoContext(ctx, "the role is authorized by the legacy node id",
+		slog.String("repository", usage.Repository),
+		slog.String("role_arn", usage.RoleARN),
+		slog.String("legacy_node_id", usage.LegacyNodeID),
+		slog.String("next_node_id", usage.NextNodeID),
+	)
+
+	if h.legacyNodeIDs == nil {
+		return
+	}
+	if err := h.legacyNodeIDs.Record(ctx, usage); err != nil {
+		h.log().WarnContext(ctx, "failed to record the usage of the legacy node id", slog.String("error", err.Error()))
+	}
+}
+
+// trustPolicyDiff returns the conditions of the trust policy that differ between the formats.
+func trustPolicyDiff(legacy, next *sts.AssumeRoleInput) []*TrustPolicyChange {
+	var changes []*TrustPolicyChange
+	if l, n := aws.ToString(legacy.ExternalId), aws.ToString(next.ExternalId); l != n {
+		changes = append(changes, &TrustPolicyChange{
+			ConditionKey: "sts:ExternalId",
+			Legacy:       l,
+			Next:         n,
+		})
+	}
+
+	nextTags := make(map[string]string, len(next.Tags))
+	for _, tag := range next.Tags {
+		nextTags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	for _, tag := range legacy.Tags {
+		key := aws.ToString(tag.Key)
+		l, n := aws.ToString(tag.Value), nextTags[key]
+		if l != n {
+			changes = append(changes, &TrustPolicyChange{
+				ConditionKey: "aws:RequestTag/" + key,
+				Legacy:       l,
+				Next:         n,
+			})
+		}
+	}
+	return changes
+}
+
+type legacyNodeIDsResponseBody struct {
+	Usages []*LegacyNodeIDUsage `json:"usages"`
+}
+
+// serveLegacyNodeIDs lists the repositories and roles that need to migrate their node IDs.
+func (h *Handler) serveLegacyNodeIDs(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(w, r); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	if h.legacyNodeIDs == nil {
+		h.handleError(w, r, &validationError{
+			message: "recording the usages of the legacy node ids is disabled",
+			code:    "NotFound",
+			status:  http.StatusNotFound,
+		})
+		return
+	}
+
+	usages, err := h.legacyNodeIDs.List(r.Context())
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(&legacyNodeIDsResponseBody{Usages: usages})
+}
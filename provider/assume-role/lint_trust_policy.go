@@ -0,0 +1,144 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/smithy-go"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/trustpolicy"
+)
+
+type lintTrustPolicyRequestBody struct {
+	// Policy is the trust policy document to analyze.
+	Policy json.RawMessage `json:"policy"`
+
+	// RoleARN is the role whose trust policy is fetched with iam:GetRole.
+	// Either Policy or RoleARN is required.
+	RoleARN string `json:"role_arn"`
+
+	// Repository is the repository that assumes the role.
+	// If it is empty, the ExternalId is not compared with the repository.
+	Repository         string `json:"repository"`
+	UseNodeID          bool   `json:"use_node_id"`
+	RoleSessionTagging bool   `json:"role_session_tagging"`
+
+	// GitHubToken is used for looking up the node id if the credential provider has no GitHub App.
+	GitHubToken string `json:"github_token"`
+}
+
+type lintTrustPolicyResponseBody struct {
+	Issues []*trustpolicy.Issue  `json:"issues"`
+	Policy *trustpolicy.Document `json:"policy"`
+}
+
+// serveLintTrustPolicy analyzes the trust policy of IAM roles.
+func (h *Handler) serveLintTrustPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.startSpan(h.extractSpanContext(r), "LintTrustPolicy")
+	var req lintTrustPolicyRequestBody
+	if err := h.decodeRequest(w, r, &req); err != nil {
+		span.End(err)
+		h.handleError(w, r, err)
+		return
+	}
+
+	resp, err := h.lintTrustPolicy(ctx, &req)
+	span.End(err)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(resp)
+}
+
+func (h *Handler) lintTrustPolicy(ctx context.Context, req *lintTrustPolicyRequestBody) (*lintTrustPolicyResponseBody, error) {
+	doc, err := h.lintTarget(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	principal, err := h.providerPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := &trustpolicy.LintOptions{
+		Principal: principal,
+		Tagging:   req.RoleSessionTagging,
+	}
+	if req.Repository != "" {
+		owner, repo, err := splitOwnerRepo(req.Repository)
+		if err != nil {
+			return nil, err
+		}
+		if err := h.checkRateLimit(ctx, req.Repository, req.RoleARN); err != nil {
+			return nil, err
+		}
+		opts.ExternalID, err = h.repositoryExternalID(ctx, owner, repo, req.UseNodeID, req.GitHubToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	issues := trustpolicy.Lint(doc, opts)
+	if issues == nil {
+		issues = []*trustpolicy.Issue{}
+	}
+	return &lintTrustPolicyResponseBody{
+		Issues: issues,
+		Policy: doc,
+	}, nil
+}
+
+// lintTarget returns the trust policy document in the request, or fetches it from IAM.
+func (h *Handler) lintTarget(ctx context.Context, req *lintTrustPolicyRequestBody) (*trustpolicy.Document, error) {
+	if (len(req.Policy) == 0) == (req.RoleARN == "") {
+		return nil, &validationError{
+			message: "either policy or role_arn is required",
+		}
+	}
+
+	if len(req.Policy) > 0 {
+		// the document may be embedded as a JSON string.
+		data := []byte(req.Policy)
+		var s string
+		if err := json.Unmarshal(data, &s); err == nil {
+			data = []byte(s)
+		}
+		doc, err := trustpolicy.Parse(data)
+		if err != nil {
+			return nil, &validationError{
+				message: fmt.Sprintf("failed to parse the policy: %v", err),
+			}
+		}
+		return doc, nil
+	}
+
+	if h.iam == nil {
+		return nil, &validationError{
+			message: "fetching trust policies is disabled on this credential provider. Pass the policy document instead of role_arn.",
+		}
+	}
+	doc, err := trustpolicy.FetchRolePolicy(ctx, h.iam, req.RoleARN)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && (ae.ErrorCode() == "NoSuchEntity" || ae.ErrorCode() == "AccessDenied") {
+			return nil, &validationError{
+				message: fmt.Sprintf("failed to get the role %s: %s", req.RoleARN, ae.ErrorMessage()),
+				code:    ae.ErrorCode(),
+			}
+		}
+		if errors.Is(err, trustpolicy.ErrInvalidRoleARN) {
+			return nil, &validationError{
+				message: err.Error(),
+			}
+		}
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,130 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/trustpolicy"
+)
+
+type iamClientMock struct {
+	GetRoleFunc func(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+}
+
+func (c *iamClientMock) GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	return c.GetRoleFunc(ctx, params, optFns...)
+}
+
+const dummyTooOpenTrustPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::053160724612:root"},"Action":"sts:AssumeRole","Condition":{"StringLike":{"sts:ExternalId":"fuller-inc/*"}}}]}`
+
+func newLintTestHandler(t *testing.T, opts ...Option) *Handler {
+	t.Helper()
+	opts = append([]Option{
+		WithGitHubClient(&githubClientDummy{}),
+		WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+			return &stsClientMock{
+				GetCallerIdentityFunc: func(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+					return &sts.GetCallerIdentityOutput{
+						Account: aws.String("053160724612"),
+					}, nil
+				},
+			}, nil
+		}),
+	}, opts...)
+	h, err := NewHandlerWithOptions(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func lintCodes(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Issues []*trustpolicy.Issue `json:"issues"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	for _, issue := range resp.Issues {
+		codes = append(codes, issue.Code)
+	}
+	return codes
+}
+
+func TestLintTrustPolicy(t *testing.T) {
+	h := newLintTestHandler(t)
+
+	body := `{"policy":` + dummyTooOpenTrustPolicy + `,"repository":"fuller-inc/actions-aws-assume-role","use_node_id":true,"role_session_tagging":true,"github_token":"ghs_dummyGitHubToken"}`
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/lint-trust-policy", strings.NewReader(body))
+	h.ServeHTTP(w, r)
+	codes := lintCodes(t, w)
+	if strings.Join(codes, ",") != "ExternalIdWildcard,MissingTagSession" {
+		t.Errorf("unexpected issues: %v", codes)
+	}
+}
+
+func TestLintTrustPolicy_RoleARN(t *testing.T) {
+	h := newLintTestHandler(t, WithIAMClient(&iamClientMock{
+		GetRoleFunc: func(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+			switch aws.ToString(params.RoleName) {
+			case "assume-role-test":
+				return &iam.GetRoleOutput{
+					Role: &types.Role{
+						AssumeRolePolicyDocument: aws.String(url.QueryEscape(dummyTooOpenTrustPolicy)),
+					},
+				}, nil
+			}
+			return nil, &smithy.GenericAPIError{Code: "NoSuchEntity", Message: "The role cannot be found."}
+		},
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/lint-trust-policy", strings.NewReader(`{"role_arn":"arn:aws:iam::123456789012:role/assume-role-test"}`))
+	h.ServeHTTP(w, r)
+	codes := lintCodes(t, w)
+	if strings.Join(codes, ",") != "ExternalIdWildcard" {
+		t.Errorf("unexpected issues: %v", codes)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/lint-trust-policy", strings.NewReader(`{"role_arn":"arn:aws:iam::123456789012:role/not-found"}`))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLintTrustPolicy_Invalid(t *testing.T) {
+	// fetching roles is disabled.
+	h := newLintTestHandler(t)
+	cases := []string{
+		`{}`,
+		`{"policy":{},"role_arn":"arn:aws:iam::123456789012:role/assume-role-test"}`,
+		`{"policy":"not a json"}`,
+		`{"role_arn":"arn:aws:iam::123456789012:role/assume-role-test"}`,
+	}
+	for _, body := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/lint-trust-policy", strings.NewReader(body))
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: unexpected status code: %d, body: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
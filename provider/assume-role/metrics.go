@@ -0,0 +1,334 @@
+package assumerole
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/metrics"
+)
+
+const (
+	outcomeSuccess     = "success"
+	outcomeClientError = "client_error"
+	outcomeServerError = "server_error"
+)
+
+// handlerMetrics is the set of metrics of Handler.
+// All methods are nil-safe, so that the handler works without metrics.
+type handlerMetrics struct {
+	requests        *metrics.CounterVec
+	requestDuration *metrics.HistogramVec
+	githubRequests  *metrics.CounterVec
+	githubDuration  *metrics.HistogramVec
+	stsRequests     *metrics.CounterVec
+	stsDuration     *metrics.HistogramVec
+	legacyNodeID    *metrics.CounterVec
+	credentialType  *metrics.CounterVec
+	jwksFetches     *metrics.CounterVec
+	probeCache      *metrics.CounterVec
+	githubToken     *metrics.CounterVec
+}
+
+func newHandlerMetrics(r *metrics.Registry) *handlerMetrics {
+	if r == nil {
+		return nil
+	}
+	return &handlerMetrics{
+		requests: r.NewCounterVec(
+			"assume_role_requests_total",
+			"The number of requests by the endpoint, the outcome and the error code.",
+			"endpoint", "outcome", "error_code",
+		),
+		requestDuration: r.NewHistogramVec(
+			"assume_role_request_duration_seconds",
+			"The latency of requests.",
+			nil, "endpoint", "outcome",
+		),
+		githubRequests: r.NewCounterVec(
+			"assume_role_github_requests_total",
+			"The number of GitHub API calls by the operation and the outcome.",
+			"operation", "outcome",
+		),
+		githubDuration: r.NewHistogramVec(
+			"assume_role_github_request_duration_seconds",
+			"The latency of GitHub API calls.",
+			nil, "operation",
+		),
+		stsRequests: r.NewCounterVec(
+			"assume_role_sts_requests_total",
+			"The number of AWS STS API calls by the operation and the error code.",
+			"operation", "error_code",
+		),
+		stsDuration: r.NewHistogramVec(
+			"assume_role_sts_request_duration_seconds",
+			"The latency of AWS STS API calls.",
+			nil, "operation",
+		),
+		legacyNodeID: r.NewCounterVec(
+			"assume_role_legacy_node_id_fallbacks_total",
+			"The number of requests that are authorized by the legacy node IDs.",
+		),
+		credentialType: r.NewCounterVec(
+			"assume_role_credential_type_total",
+			"The number of requests by the type of the credential: oidc or github_token.",
+			"type",
+		),
+		jwksFetches: r.NewCounterVec(
+			"assume_role_jwks_fetches_total",
+			"The number of fetches of the JWK Set for verifying OIDC tokens.",
+			"outcome",
+		),
+		probeCache: r.NewCounterVec(
+			"assume_role_probe_cache_lookups_total",
+			"The number of lookups of the cache of trust policy probes by the result: hit or miss.",
+			"result",
+		),
+		githubToken: r.NewCounterVec(
+			"assume_role_github_token_requests_total",
+			"The number of requests authenticated with GITHUB_TOKEN by the verified owner and the result: accepted or rejected.",
+			"owner", "result",
+		),
+	}
+}
+
+func (m *handlerMetrics) observeRequest(endpoint string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	outcome := outcomeSuccess
+	if err != nil {
+		var validation *validationError
+		var veto *VetoError
+		var rateLimit *RateLimitError
+		if errors.As(err, &validation) || errors.As(err, &veto) || errors.As(err, &rateLimit) {
+			outcome = outcomeClientError
+		} else {
+			outcome = outcomeServerError
+		}
+	}
+	m.requests.Inc(endpoint, outcome, errorCode(err))
+	m.requestDuration.Observe(time.Since(start).Seconds(), endpoint, outcome)
+}
+
+func (m *handlerMetrics) observeLegacyNodeID() {
+	if m == nil {
+		return
+	}
+	m.legacyNodeID.Inc()
+}
+
+func (m *handlerMetrics) observeProbeCache(hit bool) {
+	if m == nil {
+		return
+	}
+	if hit {
+		m.probeCache.Inc("hit")
+	} else {
+		m.probeCache.Inc("miss")
+	}
+}
+
+// observeGitHubToken records the usage of GITHUB_TOKEN.
+// owner is empty if it is not verified yet.
+func (m *handlerMetrics) observeGitHubToken(owner, result string) {
+	if m == nil {
+		return
+	}
+	m.githubToken.Inc(owner, result)
+}
+
+func (m *handlerMetrics) observeCredentialType(typ string) {
+	if m == nil {
+		return
+	}
+	m.credentialType.Inc(typ)
+}
+
+// errorCode returns a short code of err for the label of metrics.
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	var validation *validationError
+	if errors.As(err, &validation) {
+		if validation.code != "" {
+			return validation.code
+		}
+		return "ValidationError"
+	}
+	var veto *VetoError
+	if errors.As(err, &veto) {
+		return "Vetoed"
+	}
+	var rateLimit *RateLimitError
+	if errors.As(err, &rateLimit) {
+		return "RateLimited"
+	}
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		return ae.ErrorCode()
+	}
+	var unexpected *github.UnexpectedStatusCodeError
+	if errors.As(err, &unexpected) {
+		return "GitHubUnexpectedStatusCode"
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return "Timeout"
+	}
+	return "InternalError"
+}
+
+// githubClientWithMetrics records the metrics of GitHub API calls.
+type githubClientWithMetrics struct {
+	GitHubClient
+	metrics *handlerMetrics
+}
+
+func (c *githubClientWithMetrics) observe(operation string, start time.Time, err error) {
+	outcome := outcomeSuccess
+	if err != nil {
+		outcome = "error"
+	}
+	c.metrics.githubRequests.Inc(operation, outcome)
+	c.metrics.githubDuration.Observe(time.Since(start).Seconds(), operation)
+}
+
+func (c *githubClientWithMetrics) CreateStatus(ctx context.Context, token, owner, repo, ref string, status *github.CreateStatusRequest) (*github.CreateStatusResponse, error) {
+	start := time.Now()
+	resp, err := c.GitHubClient.CreateStatus(ctx, token, owner, repo, ref, status)
+	c.observe("CreateStatus", start, err)
+	return resp, err
+}
+
+func (c *githubClientWithMetrics) CreateCheckRun(ctx context.Context, token, owner, repo string, run *github.CreateCheckRunRequest) (*github.CreateCheckRunResponse, error) {
+	start := time.Now()
+	resp, err := c.GitHubClient.CreateCheckRun(ctx, token, owner, repo, run)
+	c.observe("CreateCheckRun", start, err)
+	return resp, err
+}
+
+func (c *githubClientWithMetrics) GetRepo(ctx context.Context, nextIDFormat bool, token, owner, repo string) (*github.GetRepoResponse, error) {
+	start := time.Now()
+	resp, err := c.GitHubClient.GetRepo(ctx, nextIDFormat, token, owner, repo)
+	c.observe("GetRepo", start, err)
+	return resp, err
+}
+
+func (c *githubClientWithMetrics) GetUser(ctx context.Context, nextIDFormat bool, token, user string) (*github.GetUserResponse, error) {
+	start := time.Now()
+	resp, err := c.GitHubClient.GetUser(ctx, nextIDFormat, token, user)
+	c.observe("GetUser", start, err)
+	return resp, err
+}
+
+func (c *githubClientWithMetrics) GetEnvironment(ctx context.Context, token, owner, repo, environment string) (*github.GetEnvironmentResponse, error) {
+	start := time.Now()
+	resp, err := c.GitHubClient.GetEnvironment(ctx, token, owner, repo, environment)
+	c.observe("GetEnvironment", start, err)
+	return resp, err
+}
+
+func (c *githubClientWithMetrics) ListDeployments(ctx context.Context, token, owner, repo, sha, environment string) ([]*github.Deployment, error) {
+	start := time.Now()
+	resp, err := c.GitHubClient.ListDeployments(ctx, token, owner, repo, sha, environment)
+	c.observe("ListDeployments", start, err)
+	return resp, err
+}
+
+func (c *githubClientWithMetrics) ListWorkflowRunApprovals(ctx context.Context, token, owner, repo, runID string) ([]*github.WorkflowRunApproval, error) {
+	start := time.Now()
+	resp, err := c.GitHubClient.ListWorkflowRunApprovals(ctx, token, owner, repo, runID)
+	c.observe("ListWorkflowRunApprovals", start, err)
+	return resp, err
+}
+
+func (c *githubClientWithMetrics) ParseIDToken(ctx context.Context, idToken string) (*github.ActionsIDToken, error) {
+	start := time.Now()
+	resp, err := c.GitHubClient.ParseIDToken(ctx, idToken)
+	c.observe("ParseIDToken", start, err)
+	return resp, err
+}
+
+func (c *githubClientWithMetrics) CheckJWKS(ctx context.Context) error {
+	start := time.Now()
+	err := c.GitHubClient.CheckJWKS(ctx)
+	c.observe("CheckJWKS", start, err)
+	return err
+}
+
+func (c *githubClientWithMetrics) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := c.GitHubClient.Ping(ctx)
+	c.observe("Ping", start, err)
+	return err
+}
+
+// stsClientWithMetrics records the metrics of AWS STS API calls.
+type stsClientWithMetrics struct {
+	STSClient
+	metrics *handlerMetrics
+}
+
+func (c *stsClientWithMetrics) observe(operation string, start time.Time, err error) {
+	code := errorCode(err)
+	if err == nil {
+		code = "OK"
+	}
+	c.metrics.stsRequests.Inc(operation, code)
+	c.metrics.stsDuration.Observe(time.Since(start).Seconds(), operation)
+}
+
+func (c *stsClientWithMetrics) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	start := time.Now()
+	resp, err := c.STSClient.AssumeRole(ctx, params, optFns...)
+	c.observe("AssumeRole", start, err)
+	return resp, err
+}
+
+func (c *stsClientWithMetrics) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	start := time.Now()
+	resp, err := c.STSClient.GetCallerIdentity(ctx, params, optFns...)
+	c.observe("GetCallerIdentity", start, err)
+	return resp, err
+}
+
+// jwksTransport counts the fetches of JWK Sets.
+// The fetches are cached in the OIDC client, so they can't be observed from GitHubClient.
+type jwksTransport struct {
+	base    http.RoundTripper
+	metrics *handlerMetrics
+}
+
+func (t *jwksTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if req.Header.Get("Accept") == "application/jwk-set+json" {
+		outcome := outcomeSuccess
+		if err != nil || resp.StatusCode != http.StatusOK {
+			outcome = "error"
+		}
+		t.metrics.jwksFetches.Inc(outcome)
+	}
+	return resp, err
+}
+
+// instrumentHTTPClient returns a copy of client that counts the fetches of JWK Sets.
+func (m *handlerMetrics) instrumentHTTPClient(client *http.Client) *http.Client {
+	if m == nil {
+		return client
+	}
+	c := *client
+	c.Transport = &jwksTransport{
+		base:    client.Transport,
+		metrics: m,
+	}
+	return &c
+}
@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CloudWatch accepts up to 100 values in a single metric of the Embedded Metric Format.
+const emfMaxValues = 100
+
+type emfMetadata struct {
+	Timestamp         int64                `json:"Timestamp"`
+	CloudWatchMetrics []emfMetricDirective `json:"CloudWatchMetrics"`
+}
+
+type emfMetricDirective struct {
+	Namespace  string          `json:"Namespace"`
+	Dimensions [][]string      `json:"Dimensions"`
+	Metrics    []emfMetricInfo `json:"Metrics"`
+}
+
+type emfMetricInfo struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+type emfValues struct {
+	Values []float64 `json:"Values"`
+	Counts []uint64  `json:"Counts"`
+}
+
+// WriteEMF writes the metrics that are recorded after the last call of WriteEMF
+// in the CloudWatch Embedded Metric Format, one JSON document per line.
+// It is useful for AWS Lambda, where the logs are sent to CloudWatch Logs.
+// Call EnableEMF before recording metrics to write histograms.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+func (r *Registry) WriteEMF(w io.Writer, namespace string, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, m := range r.snapshot() {
+		for _, doc := range m.collectEMF(namespace, now) {
+			if err := enc.Encode(doc); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// collectEMF returns the documents of the pending values, and resets them.
+func (m *metric) collectEMF(namespace string, now time.Time) []map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var docs []map[string]any
+	for _, s := range m.sortedSeries() {
+		switch m.typ {
+		case typeCounter:
+			if s.pendingValue == 0 {
+				continue
+			}
+			doc := m.newEMFDocument(namespace, now, s)
+			doc[m.name] = s.pendingValue
+			docs = append(docs, doc)
+			s.pendingValue = 0
+		case typeHistogram:
+			for values := range slices.Chunk(aggregateValues(s.pendingValues), emfMaxValues) {
+				doc := m.newEMFDocument(namespace, now, s)
+				v := emfValues{
+					Values: make([]float64, 0, len(values)),
+					Counts: make([]uint64, 0, len(values)),
+				}
+				for _, value := range values {
+					v.Values = append(v.Values, value.value)
+					v.Counts = append(v.Counts, value.count)
+				}
+				doc[m.name] = v
+				docs = append(docs, doc)
+			}
+			s.pendingValues = s.pendingValues[:0]
+		}
+	}
+	return docs
+}
+
+func (m *metric) newEMFDocument(namespace string, now time.Time, s *series) map[string]any {
+	doc := map[string]any{}
+
+	// CloudWatch doesn't accept empty dimension values. skip them.
+	dimensions := []string{}
+	for i, label := range m.labels {
+		if s.labelValues[i] == "" {
+			continue
+		}
+		dimensions = append(dimensions, label)
+		doc[label] = s.labelValues[i]
+	}
+
+	doc["_aws"] = emfMetadata{
+		Timestamp: now.UnixMilli(),
+		CloudWatchMetrics: []emfMetricDirective{
+			{
+				Namespace:  namespace,
+				Dimensions: [][]string{dimensions},
+				Metrics: []emfMetricInfo{
+					{
+						Name: m.name,
+						Unit: m.emfUnit(),
+					},
+				},
+			},
+		},
+	}
+	return doc
+}
+
+func (m *metric) emfUnit() string {
+	if m.typ == typeCounter {
+		return "Count"
+	}
+	if strings.HasSuffix(m.name, "_seconds") {
+		return "Seconds"
+	}
+	return "None"
+}
+
+type aggregatedValue struct {
+	value float64
+	count uint64
+}
+
+// aggregateValues counts the same values.
+func aggregateValues(values []float64) []aggregatedValue {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	var ret []aggregatedValue
+	for _, v := range sorted {
+		if len(ret) > 0 && ret[len(ret)-1].value == v {
+			ret[len(ret)-1].count++
+			continue
+		}
+		ret = append(ret, aggregatedValue{value: v, count: 1})
+	}
+	return ret
+}
@@ -0,0 +1,225 @@
+// Package metrics is a very light weight metrics library.
+// It exports metrics in the Prometheus text format, the OpenMetrics text format
+// and the CloudWatch Embedded Metric Format.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the default buckets of histograms for latencies in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type metricType int
+
+const (
+	typeCounter metricType = iota
+	typeHistogram
+)
+
+func (t metricType) String() string {
+	switch t {
+	case typeCounter:
+		return "counter"
+	case typeHistogram:
+		return "histogram"
+	}
+	return "unknown"
+}
+
+// Registry is a set of metrics.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*metric
+
+	// emf is true if the registry records observations for the Embedded Metric Format.
+	emf atomic.Bool
+}
+
+// NewRegistry returns a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// EnableEMF makes the registry record each observation of histograms for WriteEMF.
+// The observations are kept in memory until WriteEMF is called,
+// so call it only if WriteEMF is called periodically.
+func (r *Registry) EnableEMF() {
+	r.emf.Store(true)
+}
+
+// metric is a family of series that have the same name.
+type metric struct {
+	name    string
+	help    string
+	typ     metricType
+	labels  []string
+	buckets []float64
+	emf     *atomic.Bool
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is a set of values that have the same label values.
+type series struct {
+	labelValues []string
+
+	// for counters
+	value float64
+
+	// for histograms.
+	// counts[i] is the number of observations in (buckets[i-1], buckets[i]].
+	// counts[len(buckets)] is the number of observations in (buckets[len(buckets)-1], +Inf).
+	counts []uint64
+	sum    float64
+	count  uint64
+
+	// the values that have not been written in the Embedded Metric Format yet.
+	pendingValue  float64
+	pendingValues []float64
+}
+
+func (r *Registry) register(name, help string, typ metricType, buckets []float64, labels []string) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range r.metrics {
+		if m.name != name {
+			continue
+		}
+		// the metric is already registered. share it.
+		if m.typ != typ || !slices.Equal(m.labels, labels) || !slices.Equal(m.buckets, buckets) {
+			panic(fmt.Sprintf("metrics: %s is already registered with another definition", name))
+		}
+		return m
+	}
+
+	m := &metric{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  slices.Clone(labels),
+		buckets: slices.Clone(buckets),
+		emf:     &r.emf,
+		series:  map[string]*series{},
+	}
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+// snapshot returns the registered metrics sorted by their names.
+func (r *Registry) snapshot() []*metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	metrics := slices.Clone(r.metrics)
+	slices.SortFunc(metrics, func(a, b *metric) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return metrics
+}
+
+// with returns the series for the label values.
+// m.mu must be held.
+func (m *metric) with(labelValues []string) *series {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", m.name, len(m.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{
+			labelValues: slices.Clone(labelValues),
+		}
+		if m.typ == typeHistogram {
+			s.counts = make([]uint64, len(m.buckets)+1)
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+// sortedSeries returns the series sorted by their label values.
+// m.mu must be held.
+func (m *metric) sortedSeries() []*series {
+	list := make([]*series, 0, len(m.series))
+	for _, s := range m.series {
+		list = append(list, s)
+	}
+	slices.SortFunc(list, func(a, b *series) int {
+		return slices.Compare(a.labelValues, b.labelValues)
+	})
+	return list
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	m *metric
+}
+
+// NewCounterVec registers a new counter.
+// If the counter with the same name is already registered, it is returned.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		m: r.register(name, help, typeCounter, nil, labels),
+	}
+}
+
+// Inc increments the counter by 1.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v to the counter. v must not be negative.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counters can't decrease")
+	}
+	c.m.mu.Lock()
+	defer c.m.mu.Unlock()
+	s := c.m.with(labelValues)
+	s.value += v
+	s.pendingValue += v
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	m *metric
+}
+
+// NewHistogramVec registers a new histogram.
+// If buckets is nil, DefaultBuckets is used.
+// If the histogram with the same name is already registered, it is returned.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	if !slices.IsSorted(buckets) {
+		panic("metrics: buckets must be sorted")
+	}
+	return &HistogramVec{
+		m: r.register(name, help, typeHistogram, buckets, labels),
+	}
+}
+
+// Observe adds a single observation to the histogram.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	if math.IsNaN(v) {
+		return
+	}
+	h.m.mu.Lock()
+	defer h.m.mu.Unlock()
+	s := h.m.with(labelValues)
+	i, _ := slices.BinarySearch(h.m.buckets, v)
+	s.counts[i]++
+	s.sum += v
+	s.count++
+	if h.m.emf.Load() {
+		s.pendingValues = append(s.pendingValues, v)
+	}
+}
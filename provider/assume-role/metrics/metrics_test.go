@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("requests_total", "The number of requests.", "outcome")
+	c.Inc("success")
+	c.Add(2, "error")
+	h := r.NewHistogramVec("latency_seconds", "The latency.\nIn seconds.", []float64{0.1, 1}, "op")
+	h.Observe(0.05, "get")
+	h.Observe(0.1, "get")
+	h.Observe(5, "get")
+
+	// registering the same metric returns the shared one.
+	r.NewCounterVec("requests_total", "The number of requests.", "outcome").Inc("success")
+
+	var buf bytes.Buffer
+	if err := r.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP latency_seconds The latency.\nIn seconds.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.1"} 2
+latency_seconds_bucket{op="get",le="1"} 2
+latency_seconds_bucket{op="get",le="+Inf"} 3
+latency_seconds_sum{op="get"} 5.15
+latency_seconds_count{op="get"} 3
+# HELP requests_total The number of requests.
+# TYPE requests_total counter
+requests_total{outcome="error"} 2
+requests_total{outcome="success"} 2
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestServeHTTP_OpenMetrics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("requests_total", "The number of requests.").Inc()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Type"); got != contentTypeOpenMetrics {
+		t.Errorf("unexpected content type: %q", got)
+	}
+	want := `# HELP requests The number of requests.
+# TYPE requests counter
+requests_total 1
+# EOF
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	got := escapeLabelValue("a\"b\\c\nd")
+	want := `a\"b\\c\nd`
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestWriteEMF(t *testing.T) {
+	r := NewRegistry()
+	r.EnableEMF()
+	c := r.NewCounterVec("requests_total", "The number of requests.", "outcome", "error_code")
+	c.Inc("success", "")
+	c.Inc("success", "")
+	h := r.NewHistogramVec("latency_seconds", "The latency.", nil, "op")
+	h.Observe(0.5, "get")
+	h.Observe(0.5, "get")
+	h.Observe(0.25, "get")
+
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	if err := r.WriteEMF(&buf, "AssumeRole", now); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got %d: %s", len(lines), buf.String())
+	}
+
+	var hist map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &hist); err != nil {
+		t.Fatal(err)
+	}
+	if hist["op"] != "get" {
+		t.Errorf("unexpected dimension: %v", hist["op"])
+	}
+	values, _ := json.Marshal(hist["latency_seconds"])
+	if string(values) != `{"Counts":[1,2],"Values":[0.25,0.5]}` {
+		t.Errorf("unexpected values: %s", values)
+	}
+
+	var counter struct {
+		AWS struct {
+			Timestamp         int64
+			CloudWatchMetrics []struct {
+				Namespace  string
+				Dimensions [][]string
+				Metrics    []struct{ Name, Unit string }
+			}
+		} `json:"_aws"`
+		Outcome       string  `json:"outcome"`
+		RequestsTotal float64 `json:"requests_total"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &counter); err != nil {
+		t.Fatal(err)
+	}
+	if counter.AWS.Timestamp != now.UnixMilli() {
+		t.Errorf("unexpected timestamp: %d", counter.AWS.Timestamp)
+	}
+	directive := counter.AWS.CloudWatchMetrics[0]
+	if directive.Namespace != "AssumeRole" {
+		t.Errorf("unexpected namespace: %q", directive.Namespace)
+	}
+	// the empty error_code is not a dimension.
+	if len(directive.Dimensions[0]) != 1 || directive.Dimensions[0][0] != "outcome" {
+		t.Errorf("unexpected dimensions: %v", directive.Dimensions)
+	}
+	if directive.Metrics[0].Unit != "Count" {
+		t.Errorf("unexpected unit: %q", directive.Metrics[0].Unit)
+	}
+	if counter.Outcome != "success" || counter.RequestsTotal != 2 {
+		t.Errorf("unexpected counter: %q %v", counter.Outcome, counter.RequestsTotal)
+	}
+
+	// the values are reset after writing.
+	buf.Reset()
+	if err := r.WriteEMF(&buf, "AssumeRole", now); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("want no output, got %s", buf.String())
+	}
+}
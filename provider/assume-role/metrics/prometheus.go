@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	contentTypePrometheus  = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// WritePrometheus writes the metrics in the Prometheus text format.
+// https://prometheus.io/docs/instrumenting/exposition_formats/#text-based-format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	return r.writeText(w, false)
+}
+
+// WriteOpenMetrics writes the metrics in the OpenMetrics text format.
+// https://github.com/prometheus/OpenMetrics/blob/main/specification/OpenMetrics.md
+func (r *Registry) WriteOpenMetrics(w io.Writer) error {
+	return r.writeText(w, true)
+}
+
+// ServeHTTP serves the metrics for scrapers.
+// It responds in the OpenMetrics text format if the client accepts it,
+// otherwise in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	openMetrics := strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", contentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", contentTypePrometheus)
+	}
+	if err := r.writeText(w, openMetrics); err != nil {
+		slog.InfoContext(req.Context(), "failed to write metrics", slog.String("error", err.Error()))
+	}
+}
+
+func (r *Registry) writeText(w io.Writer, openMetrics bool) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range r.snapshot() {
+		m.writeText(bw, openMetrics)
+	}
+	if openMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+func (m *metric) writeText(w *bufio.Writer, openMetrics bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := m.name
+	if openMetrics && m.typ == typeCounter {
+		// OpenMetrics requires the _total suffix for the samples of counters, but not for the family name.
+		name = strings.TrimSuffix(name, "_total")
+	}
+	w.WriteString("# HELP " + name + " " + escapeHelp(m.help) + "\n")
+	w.WriteString("# TYPE " + name + " " + m.typ.String() + "\n")
+
+	for _, s := range m.sortedSeries() {
+		switch m.typ {
+		case typeCounter:
+			sample := m.name
+			if openMetrics {
+				sample = name + "_total"
+			}
+			writeSample(w, sample, m.labels, s.labelValues, "", "", s.value)
+		case typeHistogram:
+			var cumulative uint64
+			for i, bound := range m.buckets {
+				cumulative += s.counts[i]
+				writeSample(w, m.name+"_bucket", m.labels, s.labelValues, "le", formatFloat(bound), float64(cumulative))
+			}
+			writeSample(w, m.name+"_bucket", m.labels, s.labelValues, "le", "+Inf", float64(s.count))
+			writeSample(w, m.name+"_sum", m.labels, s.labelValues, "", "", s.sum)
+			writeSample(w, m.name+"_count", m.labels, s.labelValues, "", "", float64(s.count))
+		}
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels, labelValues []string, extraLabel, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + escapeLabelValue(labelValues[i]) + `"`)
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraLabel + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
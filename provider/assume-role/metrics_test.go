@@ -0,0 +1,116 @@
+package assumerole
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/metrics"
+)
+
+func TestErrorCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{&validationError{message: "invalid"}, "ValidationError"},
+		{&validationError{message: "denied", code: "AccessDenied"}, "AccessDenied"},
+		{fmt.Errorf("wrapped: %w", &smithy.GenericAPIError{Code: "Throttling"}), "Throttling"},
+		{&github.UnexpectedStatusCodeError{StatusCode: http.StatusBadGateway}, "GitHubUnexpectedStatusCode"},
+		{context.DeadlineExceeded, "Timeout"},
+		{errors.New("unknown"), "InternalError"},
+	}
+	for _, tc := range cases {
+		if got := errorCode(tc.err); got != tc.want {
+			t.Errorf("errorCode(%v): want %q, got %q", tc.err, tc.want, got)
+		}
+	}
+}
+
+func TestHandlerMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := newHandlerMetrics(registry)
+	h := &Handler{
+		github: &githubClientWithMetrics{
+			GitHubClient: &githubClientMock{
+				PingFunc: func(ctx context.Context) error {
+					return nil
+				},
+			},
+			metrics: m,
+		},
+		sts: &stsClientWithMetrics{
+			STSClient: &stsClientMock{
+				GetCallerIdentityFunc: func(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+					return nil, &smithy.GenericAPIError{Code: "ExpiredToken"}
+				},
+			},
+			metrics: m,
+		},
+		metrics: m,
+	}
+
+	// invalid request
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{"))
+	h.ServeHTTP(w, r)
+
+	h.github.Ping(context.Background())
+	h.sts.GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+
+	var buf bytes.Buffer
+	if err := registry.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`assume_role_requests_total{endpoint="assume_role",outcome="client_error",error_code="ValidationError"} 1`,
+		`assume_role_github_requests_total{operation="Ping",outcome="success"} 1`,
+		`assume_role_sts_requests_total{operation="GetCallerIdentity",error_code="ExpiredToken"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%q is not found in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestJWKSTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":[]}`))
+	}))
+	defer ts.Close()
+
+	registry := metrics.NewRegistry()
+	m := newHandlerMetrics(registry)
+	client := m.instrumentHTTPClient(ts.Client())
+
+	for _, accept := range []string{"application/jwk-set+json", "application/json"} {
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", accept)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	var buf bytes.Buffer
+	if err := registry.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `assume_role_jwks_fetches_total{outcome="success"} 1`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("%q is not found in:\n%s", want, buf.String())
+	}
+}
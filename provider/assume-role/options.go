@@ -0,0 +1,263 @@
+package assumerole
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/metrics"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/ratelimit"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/tracing"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/trustpolicy"
+)
+
+// Option configures Handler.
+type Option func(*handlerOptions)
+
+// STSClientFactory creates a new STSClient.
+type STSClientFactory func(ctx context.Context) (STSClient, error)
+
+type handlerOptions struct {
+	ctx                context.Context
+	httpClient         *http.Client
+	githubClient       GitHubClient
+	githubAPIURL       string
+	githubOIDCIssuer   string
+	stsClientFactory   STSClientFactory
+	refreshSecret      []byte
+	policies           []Policy
+	deploymentGates    []DeploymentGate
+	metrics            *metrics.Registry
+	tracer             tracing.Tracer
+	logger             *slog.Logger
+	clock              func() time.Time
+	hooks              []Hook
+	commitStatus       *CommitStatusConfig
+	checkRun           *CheckRunConfig
+	githubTokens       GitHubTokenSource
+	iamClient          trustpolicy.IAMClient
+	probeStrategies    []ProbeStrategy
+	probeCacheTTL      time.Duration
+	rateLimits         []RateLimit
+	rateLimitStore     ratelimit.Store
+	maxRequestBodySize int64
+	strictDecoding     bool
+	githubTokenAuth    *GitHubTokenAuth
+	legacyNodeIDStore  LegacyNodeIDStore
+	adminToken         []byte
+}
+
+// withContext sets the context that is used while creating the clients.
+// If it is not set, a context with 30 seconds timeout is used.
+func withContext(ctx context.Context) Option {
+	return func(o *handlerOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithHTTPClient sets the client for requests to GitHub.
+// If it is not set, the client instrumented by the tracer is used.
+// It is ignored if WithGitHubClient is set.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *handlerOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithGitHubClient sets the client of GitHub API.
+// If it is not set, *github.Client is used.
+func WithGitHubClient(client GitHubClient) Option {
+	return func(o *handlerOptions) {
+		o.githubClient = client
+	}
+}
+
+// WithGitHubAPIURL sets the URL of GitHub API.
+// If it is not set, the value of GITHUB_API_URL environment value or https://api.github.com is used.
+// It is ignored if WithGitHubClient is set.
+func WithGitHubAPIURL(url string) Option {
+	return func(o *handlerOptions) {
+		o.githubAPIURL = url
+	}
+}
+
+// WithGitHubOIDCIssuer sets the issuer of OIDC tokens.
+// If it is not set, https://token.actions.githubusercontent.com is used.
+// It is ignored if WithGitHubClient is set.
+func WithGitHubOIDCIssuer(issuer string) Option {
+	return func(o *handlerOptions) {
+		o.githubOIDCIssuer = issuer
+	}
+}
+
+// WithSTSClientFactory sets the factory of the client of AWS STS API.
+// If it is not set, the client is created from the default configuration of the AWS SDK.
+func WithSTSClientFactory(factory STSClientFactory) Option {
+	return func(o *handlerOptions) {
+		o.stsClientFactory = factory
+	}
+}
+
+// WithRefreshSecret sets the secret key for signing refresh tokens.
+// Refreshing credentials is disabled if it is empty.
+func WithRefreshSecret(secret []byte) Option {
+	return func(o *handlerOptions) {
+		o.refreshSecret = secret
+	}
+}
+
+// WithDeploymentGates sets the roles that require approved deployments.
+func WithDeploymentGates(gates []DeploymentGate) Option {
+	return func(o *handlerOptions) {
+		o.deploymentGates = gates
+	}
+}
+
+// WithPolicies sets the policies that restrict which repositories can assume which roles.
+func WithPolicies(policies []Policy) Option {
+	return func(o *handlerOptions) {
+		o.policies = policies
+	}
+}
+
+// WithMetrics sets the registry for the metrics of the handler.
+// Metrics are disabled if it is nil.
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(o *handlerOptions) {
+		o.metrics = registry
+	}
+}
+
+// WithTracer sets the tracing backend.
+// If it is not set, AWS X-Ray is used.
+func WithTracer(tracer tracing.Tracer) Option {
+	return func(o *handlerOptions) {
+		o.tracer = tracer
+	}
+}
+
+// WithLogger sets the logger.
+// If it is not set, slog.Default() is used.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *handlerOptions) {
+		o.logger = logger
+	}
+}
+
+// WithClock sets the function that returns the current time.
+// If it is not set, time.Now is used.
+func WithClock(clock func() time.Time) Option {
+	return func(o *handlerOptions) {
+		o.clock = clock
+	}
+}
+
+// WithHooks adds hooks that are called around issuing credentials.
+func WithHooks(hooks ...Hook) Option {
+	return func(o *handlerOptions) {
+		o.hooks = append(o.hooks, hooks...)
+	}
+}
+
+// WithCommitStatus enables reporting each issuance as a commit status.
+func WithCommitStatus(cfg *CommitStatusConfig) Option {
+	return func(o *handlerOptions) {
+		o.commitStatus = cfg
+	}
+}
+
+// WithCheckRun enables reporting failures as check runs.
+func WithCheckRun(cfg *CheckRunConfig) Option {
+	return func(o *handlerOptions) {
+		o.checkRun = cfg
+	}
+}
+
+// WithGitHubTokenSource sets the source of tokens for GitHub API calls made by the provider itself,
+// e.g. *github.AppTokenSource.
+// If it is not set, GITHUB_TOKEN of requests is used.
+func WithGitHubTokenSource(ts GitHubTokenSource) Option {
+	return func(o *handlerOptions) {
+		o.githubTokens = ts
+	}
+}
+
+// WithIAMClient sets the client of AWS IAM API for fetching the trust policies of roles.
+// If it is not set, the trust policy linter accepts only the documents in requests.
+func WithIAMClient(client trustpolicy.IAMClient) Option {
+	return func(o *handlerOptions) {
+		o.iamClient = client
+	}
+}
+
+// WithProbeStrategies sets the strategies for detecting trust policies that are too open.
+// If it is not set, ProbeNoExternalID and ProbeRandomExternalID are used.
+func WithProbeStrategies(strategies ...ProbeStrategy) Option {
+	return func(o *handlerOptions) {
+		o.probeStrategies = append(o.probeStrategies, strategies...)
+	}
+}
+
+// WithProbeCacheTTL enables caching the passed results of the trust policy probes.
+// The results are cached per role and the shape of the session tags for ttl.
+func WithProbeCacheTTL(ttl time.Duration) Option {
+	return func(o *handlerOptions) {
+		o.probeCacheTTL = ttl
+	}
+}
+
+// WithRateLimits sets the limits of the rate of requests per repository, owner or role.
+func WithRateLimits(limits ...RateLimit) Option {
+	return func(o *handlerOptions) {
+		o.rateLimits = append(o.rateLimits, limits...)
+	}
+}
+
+// WithRateLimitStore sets the store of the token buckets of the rate limits.
+// If it is not set, the buckets are stored in memory of the process.
+func WithRateLimitStore(store ratelimit.Store) Option {
+	return func(o *handlerOptions) {
+		o.rateLimitStore = store
+	}
+}
+
+// WithMaxRequestBodySize sets the limit of the size of request bodies in bytes.
+// If it is not set, 64 KiB is used.
+func WithMaxRequestBodySize(size int64) Option {
+	return func(o *handlerOptions) {
+		o.maxRequestBodySize = size
+	}
+}
+
+// WithStrictDecoding rejects unknown fields in request bodies,
+// so that typos such as "role_to_asume" are reported.
+func WithStrictDecoding(strict bool) Option {
+	return func(o *handlerOptions) {
+		o.strictDecoding = strict
+	}
+}
+
+// WithGitHubTokenAuth restricts the deprecated authentication with GITHUB_TOKEN.
+// If it is not set, all repositories can authenticate with GITHUB_TOKEN.
+func WithGitHubTokenAuth(auth *GitHubTokenAuth) Option {
+	return func(o *handlerOptions) {
+		o.githubTokenAuth = auth
+	}
+}
+
+// WithLegacyNodeIDStore sets the store of the usages of the legacy node IDs.
+// If it is not set, the usages are stored in memory of the process.
+func WithLegacyNodeIDStore(store LegacyNodeIDStore) Option {
+	return func(o *handlerOptions) {
+		o.legacyNodeIDStore = store
+	}
+}
+
+// WithAdminToken sets the bearer token for the admin endpoints, e.g. /admin/legacy-node-ids.
+// The admin endpoints are disabled if it is empty.
+func WithAdminToken(token []byte) Option {
+	return func(o *handlerOptions) {
+		o.adminToken = token
+	}
+}
@@ -0,0 +1,87 @@
+package assumerole
+
+import (
+	"fmt"
+)
+
+// Policy restricts which repositories can assume which roles.
+// If no policy is configured, all repositories can assume all roles.
+type Policy struct {
+	// Repositories is a list of patterns of repositories, e.g. "fuller-inc/*".
+	Repositories []string `json:"repositories"`
+
+	// Roles is a list of patterns of role ARNs, e.g. "arn:aws:iam::123456789012:role/*".
+	Roles []string `json:"roles"`
+
+	// MaxDurationSeconds is the maximum duration of sessions.
+	// Zero means the default limit of the provider.
+	MaxDurationSeconds int32 `json:"max_duration_seconds,omitempty"`
+}
+
+func (p *Policy) match(repository, role string) bool {
+	return matchAny(p.Repositories, repository) && matchAny(p.Roles, role)
+}
+
+// authorize checks whether the policies allow the repository to assume the role.
+func (h *Handler) authorize(repository, role string, durationSeconds int32) error {
+	if len(h.policies) == 0 {
+		return nil
+	}
+
+	var matched bool
+	for _, p := range h.policies {
+		if !p.match(repository, role) {
+			continue
+		}
+		matched = true
+		if p.MaxDurationSeconds == 0 || durationSeconds <= p.MaxDurationSeconds {
+			return nil
+		}
+	}
+	if matched {
+		return &validationError{
+			message: fmt.Sprintf("role-duration-seconds %d exceeds the limit of the credential provider", durationSeconds),
+		}
+	}
+	return &validationError{
+		message: fmt.Sprintf("the credential provider doesn't allow %s to assume %s", repository, role),
+	}
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern reports whether s matches the pattern.
+// '*' matches any sequence of characters including '/', and '?' matches any single character.
+func matchPattern(pattern, s string) bool {
+	// the star that we saw at last, and the position of s when we saw it.
+	star, next := -1, 0
+
+	p, i := 0, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, next = p, i
+			p++
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case star >= 0:
+			// backtrack: the last star matches one more character.
+			next++
+			p, i = star+1, next
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,74 @@
+package assumerole
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"fuller-inc/actions-aws-assume-role", "fuller-inc/actions-aws-assume-role", true},
+		{"fuller-inc/*", "fuller-inc/actions-aws-assume-role", true},
+		{"fuller-inc/*", "shogo82148/actions-aws-assume-role", false},
+		{"*", "fuller-inc/actions-aws-assume-role", true},
+		{"arn:aws:iam::123456789012:role/*", "arn:aws:iam::123456789012:role/path/to/role", true},
+		{"arn:aws:iam::123456789012:role/*", "arn:aws:iam::210987654321:role/role", false},
+		{"arn:aws:iam::*:role/deploy-*", "arn:aws:iam::123456789012:role/deploy-prod", true},
+		{"arn:aws:iam::*:role/deploy-*", "arn:aws:iam::123456789012:role/admin", false},
+		{"fuller-inc/repo-?", "fuller-inc/repo-a", true},
+		{"fuller-inc/repo-?", "fuller-inc/repo-ab", false},
+		{"*-prod", "app-prod-prod", true},
+		{"", "", true},
+		{"", "a", false},
+	}
+	for _, tc := range cases {
+		got := matchPattern(tc.pattern, tc.input)
+		if got != tc.want {
+			t.Errorf("matchPattern(%q, %q): want %t, got %t", tc.pattern, tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	h := &Handler{
+		policies: []Policy{
+			{
+				Repositories:       []string{"fuller-inc/*"},
+				Roles:              []string{"arn:aws:iam::123456789012:role/*"},
+				MaxDurationSeconds: 900,
+			},
+			{
+				Repositories: []string{"fuller-inc/actions-aws-assume-role"},
+				Roles:        []string{"arn:aws:iam::123456789012:role/long-running"},
+			},
+		},
+	}
+
+	if err := h.authorize("fuller-inc/some-repo", "arn:aws:iam::123456789012:role/deploy", 900); err != nil {
+		t.Error(err)
+	}
+	if err := h.authorize("fuller-inc/actions-aws-assume-role", "arn:aws:iam::123456789012:role/long-running", 3600); err != nil {
+		t.Error(err)
+	}
+
+	var validate *validationError
+	err := h.authorize("fuller-inc/some-repo", "arn:aws:iam::123456789012:role/deploy", 3600)
+	if !errors.As(err, &validate) {
+		t.Errorf("want validation error, got %T", err)
+	}
+	err = h.authorize("shogo82148/some-repo", "arn:aws:iam::123456789012:role/deploy", 900)
+	if !errors.As(err, &validate) {
+		t.Errorf("want validation error, got %T", err)
+	}
+}
+
+func TestAuthorize_NoPolicy(t *testing.T) {
+	h := &Handler{}
+	if err := h.authorize("fuller-inc/some-repo", "arn:aws:iam::123456789012:role/deploy", 3600); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,146 @@
+package assumerole
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// ProbeStrategy is a strategy for detecting trust policies that are too open.
+// Each strategy tries to assume the role with a wrong ExternalId,
+// and the trust policy is too open if AWS accepts it.
+type ProbeStrategy string
+
+const (
+	// ProbeNoExternalID tries to assume the role without any ExternalId.
+	// It detects trust policies without ExternalId conditions.
+	ProbeNoExternalID ProbeStrategy = "no_external_id"
+
+	// ProbeRandomExternalID tries to assume the role with a random ExternalId that is never valid.
+	// It detects wildcard ExternalId conditions, e.g. StringLike with "*".
+	ProbeRandomExternalID ProbeStrategy = "random_external_id"
+
+	// ProbeSiblingRepository tries to assume the role with the ExternalId of another repository
+	// that has the ExternalId of the repository as its prefix.
+	// It detects prefix wildcards, e.g. StringLike with "fuller-inc/*" or "R_kgDO*".
+	ProbeSiblingRepository ProbeStrategy = "sibling_repository"
+)
+
+// the prefix of random ExternalIds.
+// ':' never appears in repository names or node IDs, so the ExternalIds are never valid.
+const probeExternalIDPrefix = "aws-assume-role-probe:"
+
+// defaultProbeStrategies are used if no strategy is configured.
+var defaultProbeStrategies = []ProbeStrategy{
+	ProbeNoExternalID,
+	ProbeRandomExternalID,
+}
+
+func validateProbeStrategies(strategies []ProbeStrategy) error {
+	for _, s := range strategies {
+		switch s {
+		case ProbeNoExternalID, ProbeRandomExternalID, ProbeSiblingRepository:
+		default:
+			return fmt.Errorf("unknown probe strategy: %q", s)
+		}
+	}
+	return nil
+}
+
+// probeTrustPolicy validates the trust policy of the IAM role.
+// The passed results are cached if the probe cache is enabled.
+func (h *Handler) probeTrustPolicy(ctx context.Context, input *sts.AssumeRoleInput) error {
+	key := newProbeCacheKey(input, h.strategies())
+	if h.probeCache.passed(key, h.now()) {
+		h.metrics.observeProbeCache(true)
+		return nil
+	}
+	h.metrics.observeProbeCache(false)
+
+	if err := h.runProbes(ctx, input); err != nil {
+		return err
+	}
+	h.probeCache.store(key, h.now())
+	return nil
+}
+
+// runProbes validates the trust policy of the IAM role without the cache.
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_create_for-user_externalid.html#external-id-use
+// > In addition when a customer gives you a role ARN, test whether you can assume the role both with and without the correct external ID.
+func (h *Handler) runProbes(ctx context.Context, input *sts.AssumeRoleInput) error {
+	for _, strategy := range h.strategies() {
+		if err := h.probe(ctx, strategy, input); err != nil {
+			var validation *validationError
+			if errors.As(err, &validation) {
+				// the trust policy is too open. forget the results of other tag sets.
+				h.probeCache.invalidate(aws.ToString(input.RoleArn))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Handler) strategies() []ProbeStrategy {
+	if len(h.probeStrategies) == 0 {
+		return defaultProbeStrategies
+	}
+	return h.probeStrategies
+}
+
+func (h *Handler) probe(ctx context.Context, strategy ProbeStrategy, input *sts.AssumeRoleInput) error {
+	validationInput := &sts.AssumeRoleInput{
+		RoleArn:         input.RoleArn,
+		RoleSessionName: input.RoleSessionName,
+		Tags:            input.Tags,
+
+		// set shortest duration seconds. because we don't use this credential actually.
+		DurationSeconds: aws.Int32(900),
+
+		// request without the correct external ID
+		ExternalId: probeExternalID(strategy, aws.ToString(input.ExternalId)),
+	}
+	_, err := h.sts.AssumeRole(ctx, validationInput)
+	if err == nil {
+		return &validationError{
+			message: probeFailureMessage(strategy, aws.ToString(validationInput.ExternalId)),
+			code:    "TrustPolicyTooOpen",
+		}
+	}
+	var ae smithy.APIError
+	if !errors.As(err, &ae) || ae.ErrorCode() != "AccessDenied" {
+		// We expected AccessDenied error, but got another error. (maybe network error etc.)
+		// We can't continue this process.
+		return err
+	}
+	return nil
+}
+
+// probeExternalID returns the wrong ExternalId for the strategy.
+func probeExternalID(strategy ProbeStrategy, externalID string) *string {
+	switch strategy {
+	case ProbeRandomExternalID:
+		return aws.String(probeExternalIDPrefix + strings.ToLower(rand.Text()))
+	case ProbeSiblingRepository:
+		return aws.String(externalID + "-" + strings.ToLower(rand.Text()))
+	}
+	return nil
+}
+
+func probeFailureMessage(strategy ProbeStrategy, externalID string) string {
+	switch strategy {
+	case ProbeRandomExternalID:
+		return "The AssumeRolePolicy of your IAM Role is too open. It accepts an arbitrary ExternalId. " +
+			"Please use StringEquals for ExternalId conditions."
+	case ProbeSiblingRepository:
+		return fmt.Sprintf("The AssumeRolePolicy of your IAM Role is too open. It accepts %q as ExternalId of another repository. "+
+			"Please use StringEquals for ExternalId conditions.", externalID)
+	}
+	return "The AssumeRolePolicy of your IAM Role is too open. Please configure ExternalId conditions."
+}
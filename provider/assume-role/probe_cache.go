@@ -0,0 +1,104 @@
+package assumerole
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// the number of entries that triggers removing expired entries.
+const probeCacheSweepThreshold = 1024
+
+// probeCache remembers the roles whose trust policies passed the probes,
+// so that repeated requests skip the probes until the entry expires.
+// Only the passed results are cached; the failed probes are always retried.
+type probeCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[probeCacheKey]time.Time // -> expiresAt
+}
+
+type probeCacheKey struct {
+	roleARN string
+
+	// tags is the shape of the session tags: the sorted keys of the tags.
+	// The trust policy may have conditions on the tags.
+	tags string
+
+	// externalID is set only if the result of the probes depends on the ExternalId.
+	externalID string
+}
+
+func newProbeCache(ttl time.Duration) *probeCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &probeCache{
+		ttl:     ttl,
+		entries: make(map[probeCacheKey]time.Time),
+	}
+}
+
+func newProbeCacheKey(input *sts.AssumeRoleInput, strategies []ProbeStrategy) probeCacheKey {
+	keys := make([]string, 0, len(input.Tags))
+	for _, tag := range input.Tags {
+		keys = append(keys, aws.ToString(tag.Key))
+	}
+	slices.Sort(keys)
+	key := probeCacheKey{
+		roleARN: aws.ToString(input.RoleArn),
+		tags:    strings.Join(keys, ","),
+	}
+	if slices.Contains(strategies, ProbeSiblingRepository) {
+		key.externalID = aws.ToString(input.ExternalId)
+	}
+	return key
+}
+
+// passed reports whether the trust policy passed the probes recently.
+func (c *probeCache) passed(key probeCacheKey, now time.Time) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt, ok := c.entries[key]
+	return ok && now.Before(expiresAt)
+}
+
+// store records that the trust policy passed the probes.
+func (c *probeCache) store(key probeCacheKey, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= probeCacheSweepThreshold {
+		for k, expiresAt := range c.entries {
+			if !now.Before(expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = now.Add(c.ttl)
+}
+
+// invalidate forgets all results of the role.
+// It is called when the trust policy of the role turns out to be too open.
+func (c *probeCache) invalidate(roleARN string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.entries {
+		if k.roleARN == roleARN {
+			delete(c.entries, k)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package assumerole
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+func TestProbeCache(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	var probes int
+	open := false
+	h := &Handler{
+		sts: &stsClientMock{
+			AssumeRoleFunc: func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+				probes++
+				if open {
+					return &sts.AssumeRoleOutput{Credentials: &types.Credentials{}}, nil
+				}
+				return nil, errAccessDenied
+			},
+		},
+		clock:      func() time.Time { return now },
+		probeCache: newProbeCache(10 * time.Minute),
+	}
+	input := func(tags ...string) *sts.AssumeRoleInput {
+		in := &sts.AssumeRoleInput{
+			RoleArn:         aws.String("arn:aws:iam::123456789012:role/assume-role-test"),
+			RoleSessionName: aws.String("GitHubActions"),
+			ExternalId:      aws.String("R_kgDOFMsDjw"),
+		}
+		for _, tag := range tags {
+			in.Tags = append(in.Tags, types.Tag{Key: aws.String(tag), Value: aws.String("value-" + tag)})
+		}
+		return in
+	}
+	probe := func(in *sts.AssumeRoleInput) error {
+		probes = 0
+		return h.probeTrustPolicy(context.Background(), in)
+	}
+
+	// the first request probes the trust policy.
+	if err := probe(input("Repository", "Actor")); err != nil {
+		t.Fatal(err)
+	}
+	if probes != 2 {
+		t.Errorf("want 2 probes, got %d", probes)
+	}
+
+	// the same shape of tags hits the cache.
+	if err := probe(input("Actor", "Repository")); err != nil {
+		t.Fatal(err)
+	}
+	if probes != 0 {
+		t.Errorf("want no probes, got %d", probes)
+	}
+
+	// another shape of tags misses the cache.
+	if err := probe(input()); err != nil {
+		t.Fatal(err)
+	}
+	if probes != 2 {
+		t.Errorf("want 2 probes, got %d", probes)
+	}
+
+	// the cache expires.
+	now = now.Add(10 * time.Minute)
+	if err := probe(input("Repository", "Actor")); err != nil {
+		t.Fatal(err)
+	}
+	if probes != 2 {
+		t.Errorf("want 2 probes, got %d", probes)
+	}
+
+	// the trust policy becomes too open, and the results of the role are invalidated.
+	open = true
+	if err := probe(input("Workflow")); err == nil {
+		t.Error("want too open error, but not")
+	}
+	if err := probe(input("Repository", "Actor")); err == nil {
+		t.Error("want too open error, but not")
+	}
+	if probes != 1 {
+		t.Errorf("want 1 probe, got %d", probes)
+	}
+}
+
+func TestProbeCache_Disabled(t *testing.T) {
+	var probes int
+	h := &Handler{
+		sts: &stsClientMock{
+			AssumeRoleFunc: func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+				probes++
+				return nil, errAccessDenied
+			},
+		},
+	}
+	input := &sts.AssumeRoleInput{
+		RoleArn:    aws.String("arn:aws:iam::123456789012:role/assume-role-test"),
+		ExternalId: aws.String("R_kgDOFMsDjw"),
+	}
+	for range 2 {
+		if err := h.probeTrustPolicy(context.Background(), input); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if probes != 4 {
+		t.Errorf("want 4 probes, got %d", probes)
+	}
+}
+
+func TestNewProbeCacheKey(t *testing.T) {
+	a := &sts.AssumeRoleInput{
+		RoleArn:    aws.String("arn:aws:iam::123456789012:role/assume-role-test"),
+		ExternalId: aws.String("fuller-inc/repo-a"),
+	}
+	b := &sts.AssumeRoleInput{
+		RoleArn:    aws.String("arn:aws:iam::123456789012:role/assume-role-test"),
+		ExternalId: aws.String("fuller-inc/repo-b"),
+	}
+
+	// the results of these strategies don't depend on the ExternalId.
+	if newProbeCacheKey(a, defaultProbeStrategies) != newProbeCacheKey(b, defaultProbeStrategies) {
+		t.Error("the keys should be same")
+	}
+
+	// the sibling repository depends on the ExternalId.
+	strategies := []ProbeStrategy{ProbeSiblingRepository}
+	if newProbeCacheKey(a, strategies) == newProbeCacheKey(b, strategies) {
+		t.Error("the keys should be different")
+	}
+}
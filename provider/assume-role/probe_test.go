@@ -0,0 +1,158 @@
+package assumerole
+
+import (
+	"context"
+	"errors"
+	"path"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+// stsClientWithTrustPolicy emulates sts:AssumeRole for a trust policy.
+// accept reports whether the ExternalId condition of the trust policy is satisfied.
+func stsClientWithTrustPolicy(accept func(externalID *string) bool) *stsClientMock {
+	return &stsClientMock{
+		AssumeRoleFunc: func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+			if !accept(params.ExternalId) {
+				return nil, errAccessDenied
+			}
+			return &sts.AssumeRoleOutput{
+				Credentials: &types.Credentials{},
+			}, nil
+		},
+	}
+}
+
+// stringLike emulates a StringLike condition of sts:ExternalId.
+func stringLike(pattern string) func(externalID *string) bool {
+	return func(externalID *string) bool {
+		if externalID == nil {
+			// the condition key is missing.
+			return false
+		}
+		ok, _ := path.Match(pattern, *externalID)
+		return ok
+	}
+}
+
+func TestProbeTrustPolicy(t *testing.T) {
+	all := []ProbeStrategy{ProbeNoExternalID, ProbeRandomExternalID, ProbeSiblingRepository}
+	cases := []struct {
+		name       string
+		externalID string
+		accept     func(externalID *string) bool
+		strategies []ProbeStrategy
+		tooOpen    bool
+	}{
+		{
+			name:       "no condition",
+			externalID: "fuller-inc/actions-aws-assume-role",
+			accept:     func(externalID *string) bool { return true },
+			strategies: []ProbeStrategy{ProbeNoExternalID},
+			tooOpen:    true,
+		},
+		{
+			name:       "StringLike *",
+			externalID: "fuller-inc/actions-aws-assume-role",
+			accept:     stringLike("*"),
+			strategies: []ProbeStrategy{ProbeNoExternalID},
+			tooOpen:    false,
+		},
+		{
+			name:       "StringLike * with random external id",
+			externalID: "fuller-inc/actions-aws-assume-role",
+			accept:     stringLike("*"),
+			strategies: []ProbeStrategy{ProbeRandomExternalID},
+			tooOpen:    true,
+		},
+		{
+			name:       "StringLike * with the default strategies",
+			externalID: "fuller-inc/actions-aws-assume-role",
+			accept:     stringLike("*"),
+			tooOpen:    true,
+		},
+		{
+			name:       "StringLike owner/*",
+			externalID: "fuller-inc/actions-aws-assume-role",
+			accept:     stringLike("fuller-inc/*"),
+			tooOpen:    false,
+		},
+		{
+			name:       "StringLike owner/* with sibling repository",
+			externalID: "fuller-inc/actions-aws-assume-role",
+			accept:     stringLike("fuller-inc/*"),
+			strategies: []ProbeStrategy{ProbeSiblingRepository},
+			tooOpen:    true,
+		},
+		{
+			name:       "StringLike node id prefix with sibling repository",
+			externalID: "R_kgDOFMsDjw",
+			accept:     stringLike("R_kgDO*"),
+			strategies: []ProbeStrategy{ProbeSiblingRepository},
+			tooOpen:    true,
+		},
+		{
+			name:       "StringEquals",
+			externalID: "R_kgDOFMsDjw",
+			accept:     stringLike("R_kgDOFMsDjw"),
+			strategies: all,
+			tooOpen:    false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &Handler{
+				sts:             stsClientWithTrustPolicy(tc.accept),
+				probeStrategies: tc.strategies,
+			}
+			err := h.probeTrustPolicy(context.Background(), &sts.AssumeRoleInput{
+				RoleArn:         aws.String("arn:aws:iam::123456789012:role/assume-role-test"),
+				RoleSessionName: aws.String("GitHubActions"),
+				ExternalId:      aws.String(tc.externalID),
+				DurationSeconds: aws.Int32(3600),
+			})
+			var validation *validationError
+			tooOpen := errors.As(err, &validation) && validation.code == "TrustPolicyTooOpen"
+			if tooOpen != tc.tooOpen {
+				t.Errorf("want too open %t, got error %v", tc.tooOpen, err)
+			}
+			if !tooOpen && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestProbeTrustPolicy_UnexpectedError(t *testing.T) {
+	h := &Handler{
+		sts: &stsClientMock{
+			AssumeRoleFunc: func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+				return nil, errors.New("network error")
+			},
+		},
+	}
+	err := h.probeTrustPolicy(context.Background(), &sts.AssumeRoleInput{
+		RoleArn:    aws.String("arn:aws:iam::123456789012:role/assume-role-test"),
+		ExternalId: aws.String("fuller-inc/actions-aws-assume-role"),
+	})
+	var validation *validationError
+	if err == nil || errors.As(err, &validation) {
+		t.Errorf("want internal error, got %v", err)
+	}
+}
+
+func TestNewHandlerWithOptions_UnknownProbeStrategy(t *testing.T) {
+	_, err := NewHandlerWithOptions(
+		WithGitHubClient(&githubClientDummy{}),
+		WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+			return &stsClientDummy{}, nil
+		}),
+		WithProbeStrategies("unknown"),
+	)
+	if err == nil {
+		t.Error("want error, but not")
+	}
+}
@@ -0,0 +1,137 @@
+package assumerole
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/ratelimit"
+)
+
+// RateLimitKey is the key that requests are counted by.
+type RateLimitKey string
+
+const (
+	// RateLimitKeyRepository counts requests per verified repository, e.g. "fuller-inc/actions-aws-assume-role".
+	RateLimitKeyRepository RateLimitKey = "repository"
+
+	// RateLimitKeyOwner counts requests per owner of verified repositories, e.g. "fuller-inc".
+	RateLimitKeyOwner RateLimitKey = "owner"
+
+	// RateLimitKeyRole counts requests per role ARN.
+	RateLimitKeyRole RateLimitKey = "role"
+)
+
+// RateLimit limits the rate of requests with the token bucket algorithm.
+type RateLimit struct {
+	// Key is the key that requests are counted by.
+	Key RateLimitKey `json:"key"`
+
+	// RequestsPerMinute is the rate that the bucket is refilled.
+	RequestsPerMinute float64 `json:"requests_per_minute"`
+
+	// Burst is the size of the bucket.
+	// Zero means 1.
+	Burst int `json:"burst,omitempty"`
+}
+
+func (l *RateLimit) limit() ratelimit.Limit {
+	burst := l.Burst
+	if burst == 0 {
+		burst = 1
+	}
+	return ratelimit.Limit{
+		Rate:  l.RequestsPerMinute / 60,
+		Burst: burst,
+	}
+}
+
+func validateRateLimits(limits []RateLimit) error {
+	for i, l := range limits {
+		switch l.Key {
+		case RateLimitKeyRepository, RateLimitKeyOwner, RateLimitKeyRole:
+		default:
+			return fmt.Errorf("rate_limits[%d]: unknown key: %q", i, l.Key)
+		}
+		if !(l.RequestsPerMinute > 0) || math.IsInf(l.RequestsPerMinute, 0) {
+			return fmt.Errorf("rate_limits[%d]: requests_per_minute must be positive", i)
+		}
+		if l.Burst < 0 {
+			return fmt.Errorf("rate_limits[%d]: burst must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// RateLimitError is returned when the request exceeds the rate limits.
+type RateLimitError struct {
+	// RetryAfter is how long the caller should wait before retrying.
+	RetryAfter time.Duration
+}
+
+func (err *RateLimitError) Error() string {
+	return fmt.Sprintf("too many requests, please retry after %s", err.RetryAfter)
+}
+
+// retryAfterSeconds returns the value of the Retry-After header.
+func (err *RateLimitError) retryAfterSeconds() string {
+	seconds := int64(math.Ceil(err.RetryAfter.Seconds()))
+	return strconv.FormatInt(max(seconds, 1), 10)
+}
+
+// checkRateLimit takes a token from the buckets of the verified repository and the role.
+// The store is shared with the other requests, so errors of the store don't block issuing credentials.
+// A token is taken from every bucket even if another bucket is empty, and it is not refunded;
+// so the rejected requests are also counted.
+func (h *Handler) checkRateLimit(ctx context.Context, repository, role string) error {
+	if len(h.rateLimits) == 0 || h.rateLimitStore == nil {
+		return nil
+	}
+
+	// the names of repositories and owners are case-insensitive,
+	// so "Fuller-Inc/Foo" and "fuller-inc/foo" share the buckets.
+	repository = strings.ToLower(repository)
+
+	now := h.now()
+	var limited bool
+	var retryAfter time.Duration
+	for i, l := range h.rateLimits {
+		var value string
+		switch l.Key {
+		case RateLimitKeyRepository:
+			value = repository
+		case RateLimitKeyOwner:
+			value, _, _ = strings.Cut(repository, "/")
+		case RateLimitKeyRole:
+			value = role
+		}
+		if value == "" {
+			// e.g. the requests for generating trust policies have no role.
+			continue
+		}
+		// the index distinguishes the buckets of the limits that have the same key.
+		key := strconv.Itoa(i) + ":" + string(l.Key) + ":" + value
+		ret, err := h.rateLimitStore.Take(ctx, key, l.limit(), now)
+		if err != nil {
+			h.log().WarnContext(ctx, "failed to check the rate limit", slog.String("key", key), slog.String("error", err.Error()))
+			continue
+		}
+		if !ret.Allowed {
+			limited = true
+			retryAfter = max(retryAfter, ret.RetryAfter)
+		}
+	}
+	if limited {
+		h.log().InfoContext(ctx, "rate limited",
+			slog.String("repository", repository),
+			slog.String("role_to_assume", role),
+			slog.Duration("retry_after", retryAfter),
+		)
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// the number of buckets that triggers removing full buckets.
+const memorySweepThreshold = 4096
+
+// MemoryStore stores token buckets in the memory of the process.
+// The limits are not shared between processes.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+}
+
+type memoryBucket struct {
+	bucket
+	limit Limit
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a new MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (*Result, error) {
+	ms := now.UnixMilli()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= memorySweepThreshold {
+			s.sweep(ms)
+		}
+		b = &memoryBucket{
+			bucket: bucket{tokens: float64(limit.Burst), updated: ms},
+		}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	return b.take(limit, ms), nil
+}
+
+// sweep removes full buckets. They are same as new buckets.
+func (s *MemoryStore) sweep(now int64) {
+	for key, b := range s.buckets {
+		if b.full(b.limit, now) {
+			delete(s.buckets, key)
+		}
+	}
+}
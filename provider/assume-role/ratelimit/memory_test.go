@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testStore runs the common tests of Store.
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Rate: 1, Burst: 2}
+
+	take := func(key string, now time.Time) *Result {
+		t.Helper()
+		ret, err := s.Take(ctx, key, limit, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ret
+	}
+
+	// the bucket is full at first.
+	for i := range 2 {
+		if ret := take("repository:fuller-inc/a", now); !ret.Allowed {
+			t.Errorf("%d: want allowed, but not", i)
+		}
+	}
+	ret := take("repository:fuller-inc/a", now)
+	if ret.Allowed {
+		t.Error("want denied, but allowed")
+	}
+	if ret.RetryAfter != time.Second {
+		t.Errorf("want retry after 1s, got %s", ret.RetryAfter)
+	}
+
+	// other keys have their own buckets.
+	if ret := take("repository:fuller-inc/b", now); !ret.Allowed {
+		t.Error("want allowed, but not")
+	}
+
+	// the bucket is refilled.
+	ret = take("repository:fuller-inc/a", now.Add(500*time.Millisecond))
+	if ret.Allowed {
+		t.Error("want denied, but allowed")
+	}
+	if ret.RetryAfter != 500*time.Millisecond {
+		t.Errorf("want retry after 500ms, got %s", ret.RetryAfter)
+	}
+	if ret := take("repository:fuller-inc/a", now.Add(time.Second)); !ret.Allowed {
+		t.Error("want allowed, but not")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	limit := Limit{Rate: 1, Burst: 1}
+	for i := range memorySweepThreshold {
+		if _, err := s.Take(ctx, string(rune('a'+i%26))+time.Duration(i).String(), limit, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Take(ctx, "new", limit, now.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.buckets) != 1 {
+		t.Errorf("want 1 bucket, got %d", len(s.buckets))
+	}
+}
@@ -0,0 +1,61 @@
+// Package ratelimit provides token bucket rate limiters with pluggable stores.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is the configuration of a token bucket.
+type Limit struct {
+	// Rate is the number of tokens that are added to the bucket per second.
+	Rate float64
+
+	// Burst is the size of the bucket.
+	Burst int
+}
+
+// Result is the result of Store.Take.
+type Result struct {
+	// Allowed reports whether a token is taken.
+	Allowed bool
+
+	// RetryAfter is the time until a token is available.
+	// It is zero if Allowed is true.
+	RetryAfter time.Duration
+}
+
+// Store stores the state of token buckets.
+type Store interface {
+	// Take takes a token from the bucket of the key.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (*Result, error)
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	tokens float64
+
+	// updated is the time when tokens is updated, in milliseconds since the Unix epoch.
+	updated int64
+}
+
+// take refills the bucket and takes a token.
+// The algorithm must be same as tokenBucketScript of RedisStore.
+func (b *bucket) take(limit Limit, now int64) *Result {
+	elapsed := max(0, now-b.updated)
+	b.tokens = min(float64(limit.Burst), b.tokens+float64(elapsed)*limit.Rate/1000)
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return &Result{Allowed: true}
+	}
+	retry := math.Ceil((1 - b.tokens) * 1000 / limit.Rate)
+	return &Result{RetryAfter: time.Duration(retry) * time.Millisecond}
+}
+
+// full reports whether the bucket is full at now.
+func (b *bucket) full(limit Limit, now int64) bool {
+	elapsed := max(0, now-b.updated)
+	return b.tokens+float64(elapsed)*limit.Rate/1000 >= float64(limit.Burst)
+}
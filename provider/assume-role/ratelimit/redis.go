@@ -0,0 +1,165 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// the default prefix of keys.
+	defaultRedisPrefix = "aws-assume-role:ratelimit:"
+
+	// the default timeout for connecting to the server.
+	defaultRedisDialTimeout = 5 * time.Second
+
+	// the maximum number of idle connections.
+	maxIdleRedisConns = 8
+)
+
+// tokenBucketScript takes a token from the bucket atomically.
+// The algorithm must be same as bucket.take.
+//
+//	KEYS[1]: the key of the bucket
+//	ARGV[1]: the rate per second
+//	ARGV[2]: the burst
+//	ARGV[3]: the current time in milliseconds
+//
+// It returns {allowed (0 or 1), retry after in milliseconds}.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+  tokens = burst
+  updated = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate / 1000)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, retry}
+`
+
+// tokenBucket runs tokenBucketScript with EVALSHA,
+// and falls back to EVAL if the server doesn't have the script yet.
+var tokenBucket = redis.NewScript(tokenBucketScript)
+
+// RedisConfig is configure for RedisStore.
+type RedisConfig struct {
+	// Addr is the address of the server, e.g. "localhost:6379".
+	Addr string
+
+	// Username and Password are used for AUTH if Password is not empty.
+	Username string
+	Password string
+
+	// DB is the database number.
+	DB int
+
+	// Prefix is the prefix of keys.
+	// If it is empty, "aws-assume-role:ratelimit:" is used.
+	Prefix string
+
+	// TLSConfig enables TLS if it is not nil.
+	TLSConfig *tls.Config
+
+	// DialTimeout is the timeout for connecting to the server.
+	// If it is zero, 5 seconds is used.
+	DialTimeout time.Duration
+}
+
+// RedisStore stores token buckets in a server that speaks the Redis protocol (RESP),
+// so that the limits are shared between processes.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// RedisError is an error reply from the server.
+type RedisError struct {
+	Message string
+}
+
+func (err *RedisError) Error() string {
+	return "ratelimit: redis: " + err.Message
+}
+
+// NewRedisStore returns a new RedisStore.
+// It doesn't connect to the server until it is used.
+func NewRedisStore(cfg *RedisConfig) (*RedisStore, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("ratelimit: redis address is required")
+	}
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = defaultRedisPrefix
+	}
+	dialTimeout := cfg.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = defaultRedisDialTimeout
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Addr,
+		Username:     cfg.Username,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		TLSConfig:    cfg.TLSConfig,
+		DialTimeout:  dialTimeout,
+		MaxIdleConns: maxIdleRedisConns,
+
+		// the handler allows requests if the server is unavailable,
+		// so retries only delay the requests.
+		MaxRetries:    -1,
+		DialerRetries: 1,
+
+		// some servers that speak the Redis protocol don't support CLIENT SETINFO.
+		DisableIdentity: true,
+	})
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}, nil
+}
+
+// Take implements Store.
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (*Result, error) {
+	values, err := tokenBucket.Run(ctx, s.client, []string{s.prefix + key},
+		limit.Rate, limit.Burst, now.UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		var redisErr redis.Error
+		if errors.As(err, &redisErr) {
+			return nil, &RedisError{Message: redisErr.Error()}
+		}
+		return nil, err
+	}
+	if len(values) != 2 {
+		return nil, fmt.Errorf("ratelimit: unexpected reply: %v", values)
+	}
+	if values[0] == 1 {
+		return &Result{Allowed: true}, nil
+	}
+	return &Result{RetryAfter: time.Duration(values[1]) * time.Millisecond}, nil
+}
+
+// Close closes the connections.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
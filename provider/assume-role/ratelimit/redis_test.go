@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	s, err := NewRedisStore(&RedisConfig{
+		Addr: server.Addr(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testStore(t, s)
+
+	// the keys have the prefix.
+	if !server.Exists("aws-assume-role:ratelimit:repository:fuller-inc/a") {
+		t.Errorf("unexpected keys: %v", server.Keys())
+	}
+}
+
+func TestRedisStore_Auth(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	s, err := NewRedisStore(&RedisConfig{
+		Addr:     server.Addr(),
+		Password: "secret",
+		DB:       1,
+		Prefix:   "test:",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	for range 2 {
+		ret, err := s.Take(ctx, "owner:fuller-inc", Limit{Rate: 1, Burst: 10}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ret.Allowed {
+			t.Error("want allowed, but not")
+		}
+	}
+
+	// the bucket is stored in the database.
+	if !server.DB(1).Exists("test:owner:fuller-inc") {
+		t.Errorf("unexpected keys: %v", server.DB(1).Keys())
+	}
+}
+
+func TestRedisStore_WrongPassword(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	s, err := NewRedisStore(&RedisConfig{
+		Addr:     server.Addr(),
+		Password: "wrong",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err = s.Take(context.Background(), "owner:fuller-inc", Limit{Rate: 1, Burst: 10}, time.Now())
+	var redisErr *RedisError
+	if !errors.As(err, &redisErr) {
+		t.Errorf("want RedisError, got %v", err)
+	}
+}
+
+func TestRedisStore_Unavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s, err := NewRedisStore(&RedisConfig{Addr: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.Take(context.Background(), "owner:fuller-inc", Limit{Rate: 1, Burst: 10}, time.Now()); err == nil {
+		t.Error("want error, but not")
+	}
+}
@@ -0,0 +1,180 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	h, err := NewHandlerWithOptions(
+		WithGitHubClient(&githubClientDummy{}),
+		WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+			return &stsClientDummy{}, nil
+		}),
+		WithClock(func() time.Time { return now }),
+		WithRateLimits(
+			RateLimit{Key: RateLimitKeyRepository, RequestsPerMinute: 6, Burst: 2},
+			RateLimit{Key: RateLimitKeyRole, RequestsPerMinute: 60, Burst: 10},
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(dummyAssumeRoleRequest))
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := range 2 {
+		if w := serve(); w.Code != http.StatusOK {
+			t.Fatalf("%d: unexpected status code: %d, body: %s", i, w.Code, w.Body.String())
+		}
+	}
+
+	w := serve()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("unexpected Retry-After: %q", got)
+	}
+	var resp errorResponseBody
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message == "" {
+		t.Error("the message is empty")
+	}
+
+	// the bucket is refilled.
+	now = now.Add(10 * time.Second)
+	if w := serve(); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	h := &Handler{
+		clock: func() time.Time { return now },
+		rateLimits: []RateLimit{
+			{Key: RateLimitKeyOwner, RequestsPerMinute: 1},
+		},
+		rateLimitStore: ratelimit.NewMemoryStore(),
+	}
+	ctx := context.Background()
+	role := "arn:aws:iam::123456789012:role/assume-role-test"
+
+	if err := h.checkRateLimit(ctx, "fuller-inc/foo", role); err != nil {
+		t.Fatal(err)
+	}
+
+	// the repositories of the same owner share the bucket.
+	err := h.checkRateLimit(ctx, "fuller-inc/bar", role)
+	var rateLimit *RateLimitError
+	if !errors.As(err, &rateLimit) {
+		t.Fatalf("want RateLimitError, got %v", err)
+	}
+	if rateLimit.RetryAfter != time.Minute {
+		t.Errorf("want retry after 1m, got %s", rateLimit.RetryAfter)
+	}
+	if got := errorCode(err); got != "RateLimited" {
+		t.Errorf("unexpected error code: %q", got)
+	}
+
+	// other owners have their own buckets.
+	if err := h.checkRateLimit(ctx, "shogo82148/foo", role); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRateLimit_CaseInsensitive(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	h := &Handler{
+		clock: func() time.Time { return now },
+		rateLimits: []RateLimit{
+			{Key: RateLimitKeyRepository, RequestsPerMinute: 1},
+		},
+		rateLimitStore: ratelimit.NewMemoryStore(),
+	}
+	ctx := context.Background()
+	role := "arn:aws:iam::123456789012:role/assume-role-test"
+
+	if err := h.checkRateLimit(ctx, "fuller-inc/foo", role); err != nil {
+		t.Fatal(err)
+	}
+
+	// the names that differ only in case share the bucket.
+	for _, repository := range []string{"Fuller-Inc/Foo", "FULLER-INC/foo"} {
+		err := h.checkRateLimit(ctx, repository, role)
+		var rateLimit *RateLimitError
+		if !errors.As(err, &rateLimit) {
+			t.Errorf("%s: want RateLimitError, got %v", repository, err)
+		}
+	}
+}
+
+type rateLimitStoreFunc func(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (*ratelimit.Result, error)
+
+func (f rateLimitStoreFunc) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (*ratelimit.Result, error) {
+	return f(ctx, key, limit, now)
+}
+
+func TestCheckRateLimit_StoreError(t *testing.T) {
+	var keys []string
+	h := &Handler{
+		rateLimits: []RateLimit{
+			{Key: RateLimitKeyRepository, RequestsPerMinute: 1},
+			{Key: RateLimitKeyRole, RequestsPerMinute: 1},
+		},
+		rateLimitStore: rateLimitStoreFunc(func(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (*ratelimit.Result, error) {
+			keys = append(keys, key)
+			return nil, errors.New("connection refused")
+		}),
+	}
+
+	// errors of the store don't block issuing credentials.
+	if err := h.checkRateLimit(context.Background(), "fuller-inc/foo", "arn:aws:iam::123456789012:role/assume-role-test"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"0:repository:fuller-inc/foo",
+		"1:role:arn:aws:iam::123456789012:role/assume-role-test",
+	}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestValidateRateLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits []RateLimit
+		ok     bool
+	}{
+		{"valid", []RateLimit{{Key: RateLimitKeyRepository, RequestsPerMinute: 10, Burst: 5}}, true},
+		{"unknown key", []RateLimit{{Key: "actor", RequestsPerMinute: 10}}, false},
+		{"zero rate", []RateLimit{{Key: RateLimitKeyRole}}, false},
+		{"negative burst", []RateLimit{{Key: RateLimitKeyOwner, RequestsPerMinute: 10, Burst: -1}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRateLimits(tt.limits)
+			if (err == nil) != tt.ok {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package assumerole
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/aws/smithy-go"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+)
+
+const (
+	// the prefix of refresh tokens. it is used for versioning the format.
+	refreshTokenPrefix = "v1."
+
+	// the maximum execution time of a job on GitHub-hosted runners.
+	// https://docs.github.com/en/actions/learn-github-actions/usage-limits-billing-and-administration#usage-limits
+	refreshTokenLifetime = 6 * time.Hour
+)
+
+// refreshTokenPayload is the content of a refresh token.
+// It has everything to call sts:AssumeRole again,
+// and the provider signs it so that the callers can't modify it.
+type refreshTokenPayload struct {
+	Repository      string            `json:"repository"`
+	RunID           string            `json:"run_id"`
+	RunAttempt      string            `json:"run_attempt"`
+	RoleToAssume    string            `json:"role_to_assume"`
+	RoleSessionName string            `json:"role_session_name"`
+	ExternalID      string            `json:"external_id"`
+	DurationSeconds int32             `json:"duration_seconds"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	ExpiresAt       int64             `json:"expires_at"`
+}
+
+type refreshRequestBody struct {
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+
+	// GitHubToken is used only for GitHub API calls such as verifying deployments.
+	// It doesn't authenticate the request.
+	GitHubToken  string `json:"github_token"`
+	OutputFormat string `json:"output_format"`
+}
+
+// issueRefreshToken issues a refresh token for the credential assumed by input.
+// It returns an empty string if the refresh is disabled.
+func (h *Handler) issueRefreshToken(idToken *github.ActionsIDToken, input *sts.AssumeRoleInput) (string, error) {
+	if len(h.refreshSecret) == 0 || idToken == nil {
+		return "", nil
+	}
+
+	payload := &refreshTokenPayload{
+		Repository:      idToken.Repository,
+		RunID:           idToken.RunID,
+		RunAttempt:      idToken.RunAttempt,
+		RoleToAssume:    aws.ToString(input.RoleArn),
+		RoleSessionName: aws.ToString(input.RoleSessionName),
+		ExternalID:      aws.ToString(input.ExternalId),
+		DurationSeconds: aws.ToInt32(input.DurationSeconds),
+		ExpiresAt:       h.now().Add(refreshTokenLifetime).Unix(),
+	}
+	if len(input.Tags) > 0 {
+		payload.Tags = make(map[string]string, len(input.Tags))
+		for _, tag := range input.Tags {
+			payload.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return refreshTokenPrefix + encoded + "." + h.signRefreshToken(encoded), nil
+}
+
+func (h *Handler) signRefreshToken(encoded string) string {
+	mac := hmac.New(sha256.New, h.refreshSecret)
+	mac.Write([]byte(refreshTokenPrefix))
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseRefreshToken verifies the signature and the expiration of the token.
+func (h *Handler) parseRefreshToken(token string) (*refreshTokenPayload, error) {
+	invalid := &validationError{
+		message: "invalid refresh token",
+	}
+
+	rest, ok := strings.CutPrefix(token, refreshTokenPrefix)
+	if !ok {
+		return nil, invalid
+	}
+	encoded, signature, ok := strings.Cut(rest, ".")
+	if !ok {
+		return nil, invalid
+	}
+	if !hmac.Equal([]byte(signature), []byte(h.signRefreshToken(encoded))) {
+		return nil, invalid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+	var payload refreshTokenPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, invalid
+	}
+	if h.now().Unix() >= payload.ExpiresAt {
+		return nil, &validationError{
+			message: "the refresh token is expired, please run the action again",
+		}
+	}
+	return &payload, nil
+}
+
+func (h *Handler) refresh(ctx context.Context, req *refreshRequestBody) (*responseBody, error) {
+	if len(h.refreshSecret) == 0 {
+		return nil, &validationError{
+			message: "the credential provider doesn't support refreshing credentials",
+		}
+	}
+	if req.RefreshToken == "" {
+		return nil, &validationError{
+			message: "missing required parameter: refresh_token",
+		}
+	}
+	if err := validateOutputFormat(req.OutputFormat); err != nil {
+		return nil, err
+	}
+	payload, err := h.parseRefreshToken(req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := h.validateRun(ctx, payload, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.checkRateLimit(ctx, payload.Repository, payload.RoleToAssume); err != nil {
+		return nil, err
+	}
+
+	// the policies, the deployment gates and the hooks may be changed after the token is issued.
+	issueReq := &requestBody{
+		GitHubToken:     req.GitHubToken,
+		IDToken:         req.IDToken,
+		RoleToAssume:    payload.RoleToAssume,
+		RoleSessionName: payload.RoleSessionName,
+		DurationSeconds: payload.DurationSeconds,
+		Repository:      idToken.Repository,
+		SHA:             idToken.SHA,
+		RunID:           idToken.RunID,
+		Workflow:        idToken.Workflow,
+		Actor:           idToken.Actor,
+		Branch:          idToken.Ref,
+	}
+	return h.issueWith(ctx, idToken, issueReq, func(ctx context.Context) (*responseBody, error) {
+		return h.assumeRoleWithRefreshToken(ctx, payload)
+	})
+}
+
+// assumeRoleWithRefreshToken assumes the role with the ExternalId and the session tags in the refresh token.
+func (h *Handler) assumeRoleWithRefreshToken(ctx context.Context, payload *refreshTokenPayload) (*responseBody, error) {
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(payload.RoleToAssume),
+		RoleSessionName: aws.String(payload.RoleSessionName),
+		ExternalId:      aws.String(payload.ExternalID),
+		DurationSeconds: aws.Int32(payload.DurationSeconds),
+	}
+	for key, value := range payload.Tags {
+		input.Tags = append(input.Tags, types.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(value),
+		})
+	}
+	resp, err := h.sts.AssumeRole(ctx, input)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && ae.ErrorCode() == "AccessDenied" {
+			return nil, &validationError{
+				message: fmt.Sprintf("AWS denied your access: %s", ae.ErrorMessage()),
+			}
+		}
+		return nil, err
+	}
+	h.log().InfoContext(
+		ctx, "refreshed the credential",
+		slog.String("repository", payload.Repository),
+		slog.String("run_id", payload.RunID),
+		slog.String("run_attempt", payload.RunAttempt),
+		slog.String("role_to_assume", payload.RoleToAssume),
+	)
+	return &responseBody{
+		AccessKeyId:     aws.ToString(resp.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(resp.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(resp.Credentials.SessionToken),
+		Expiration:      aws.ToTime(resp.Credentials.Expiration),
+	}, nil
+}
+
+// validateRun checks that the job that the refresh token is issued for is still running.
+// Only the OIDC token proves it, because it is available only while the job is running
+// and it has the run attempt as its claim.
+// GITHUB_TOKEN is not bound to the run, so it doesn't authenticate refresh requests.
+func (h *Handler) validateRun(ctx context.Context, payload *refreshTokenPayload, req *refreshRequestBody) (*github.ActionsIDToken, error) {
+	if req.IDToken == "" {
+		return nil, &validationError{
+			message: "missing required parameter: id_token. Add `id-token: write` to the permissions of the job.",
+		}
+	}
+	idToken, err := h.github.ParseIDToken(ctx, req.IDToken)
+	if err != nil {
+		return nil, &validationError{
+			message: fmt.Sprintf("invalid oidc token: %v", err),
+		}
+	}
+	if idToken.Repository != payload.Repository || idToken.RunID != payload.RunID || idToken.RunAttempt != payload.RunAttempt {
+		return nil, &validationError{
+			message: "the refresh token is not issued for this workflow run",
+		}
+	}
+	return idToken, nil
+}
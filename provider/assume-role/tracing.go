@@ -0,0 +1,165 @@
+package assumerole
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/tracing"
+)
+
+// startSpan starts a new span. It works even if the tracer is not configured.
+// Never add secrets, such as tokens and credentials, to the attributes.
+func (h *Handler) startSpan(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+	if h.tracer == nil {
+		return tracing.Noop().Start(ctx, name, attrs...)
+	}
+	return h.tracer.Start(ctx, name, attrs...)
+}
+
+// extractSpanContext returns the context of the request with the propagated span context.
+func (h *Handler) extractSpanContext(r *http.Request) context.Context {
+	if h.tracer == nil {
+		return r.Context()
+	}
+	return h.tracer.Extract(r.Context(), r.Header)
+}
+
+// githubClientWithTracing records a span for each GitHub API call.
+type githubClientWithTracing struct {
+	GitHubClient
+	tracer tracing.Tracer
+}
+
+func (c *githubClientWithTracing) CreateStatus(ctx context.Context, token, owner, repo, ref string, status *github.CreateStatusRequest) (*github.CreateStatusResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "github.CreateStatus",
+		tracing.String("github.repository", owner+"/"+repo),
+		tracing.String("github.sha", ref),
+		tracing.String("github.status.state", string(status.State)),
+	)
+	resp, err := c.GitHubClient.CreateStatus(ctx, token, owner, repo, ref, status)
+	span.End(err)
+	return resp, err
+}
+
+func (c *githubClientWithTracing) CreateCheckRun(ctx context.Context, token, owner, repo string, run *github.CreateCheckRunRequest) (*github.CreateCheckRunResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "github.CreateCheckRun",
+		tracing.String("github.repository", owner+"/"+repo),
+		tracing.String("github.sha", run.HeadSHA),
+		tracing.String("github.check_run.conclusion", string(run.Conclusion)),
+	)
+	resp, err := c.GitHubClient.CreateCheckRun(ctx, token, owner, repo, run)
+	span.End(err)
+	return resp, err
+}
+
+func (c *githubClientWithTracing) GetRepo(ctx context.Context, nextIDFormat bool, token, owner, repo string) (*github.GetRepoResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "github.GetRepo",
+		tracing.String("github.repository", owner+"/"+repo),
+		tracing.Bool("github.next_id_format", nextIDFormat),
+	)
+	resp, err := c.GitHubClient.GetRepo(ctx, nextIDFormat, token, owner, repo)
+	span.End(err)
+	return resp, err
+}
+
+func (c *githubClientWithTracing) GetUser(ctx context.Context, nextIDFormat bool, token, user string) (*github.GetUserResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "github.GetUser",
+		tracing.String("github.user", user),
+		tracing.Bool("github.next_id_format", nextIDFormat),
+	)
+	resp, err := c.GitHubClient.GetUser(ctx, nextIDFormat, token, user)
+	span.End(err)
+	return resp, err
+}
+
+func (c *githubClientWithTracing) GetEnvironment(ctx context.Context, token, owner, repo, environment string) (*github.GetEnvironmentResponse, error) {
+	ctx, span := c.tracer.Start(ctx, "github.GetEnvironment",
+		tracing.String("github.repository", owner+"/"+repo),
+		tracing.String("github.environment", environment),
+	)
+	resp, err := c.GitHubClient.GetEnvironment(ctx, token, owner, repo, environment)
+	span.End(err)
+	return resp, err
+}
+
+func (c *githubClientWithTracing) ListDeployments(ctx context.Context, token, owner, repo, sha, environment string) ([]*github.Deployment, error) {
+	ctx, span := c.tracer.Start(ctx, "github.ListDeployments",
+		tracing.String("github.repository", owner+"/"+repo),
+		tracing.String("github.sha", sha),
+		tracing.String("github.environment", environment),
+	)
+	resp, err := c.GitHubClient.ListDeployments(ctx, token, owner, repo, sha, environment)
+	span.End(err)
+	return resp, err
+}
+
+func (c *githubClientWithTracing) ListWorkflowRunApprovals(ctx context.Context, token, owner, repo, runID string) ([]*github.WorkflowRunApproval, error) {
+	ctx, span := c.tracer.Start(ctx, "github.ListWorkflowRunApprovals",
+		tracing.String("github.repository", owner+"/"+repo),
+		tracing.String("github.run_id", runID),
+	)
+	resp, err := c.GitHubClient.ListWorkflowRunApprovals(ctx, token, owner, repo, runID)
+	span.End(err)
+	return resp, err
+}
+
+func (c *githubClientWithTracing) ParseIDToken(ctx context.Context, idToken string) (*github.ActionsIDToken, error) {
+	ctx, span := c.tracer.Start(ctx, "github.VerifyIDToken")
+	resp, err := c.GitHubClient.ParseIDToken(ctx, idToken)
+	if err == nil {
+		span.SetAttributes(
+			tracing.String("github.repository", resp.Repository),
+			tracing.String("github.run_id", resp.RunID),
+			tracing.String("github.workflow", resp.Workflow),
+		)
+	}
+	span.End(err)
+	return resp, err
+}
+
+func (c *githubClientWithTracing) CheckJWKS(ctx context.Context) error {
+	ctx, span := c.tracer.Start(ctx, "github.CheckJWKS")
+	err := c.GitHubClient.CheckJWKS(ctx)
+	span.End(err)
+	return err
+}
+
+func (c *githubClientWithTracing) Ping(ctx context.Context) error {
+	ctx, span := c.tracer.Start(ctx, "github.Ping")
+	err := c.GitHubClient.Ping(ctx)
+	span.End(err)
+	return err
+}
+
+// stsClientWithTracing records a span for each AWS STS API call.
+type stsClientWithTracing struct {
+	STSClient
+	tracer tracing.Tracer
+}
+
+func (c *stsClientWithTracing) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	// the external id is not a secret, but it is enough to record whether it is set
+	// to distinguish the attempts with and without it.
+	ctx, span := c.tracer.Start(ctx, "sts.AssumeRole",
+		tracing.String("aws.iam.role_arn", aws.ToString(params.RoleArn)),
+		tracing.String("aws.sts.role_session_name", aws.ToString(params.RoleSessionName)),
+		tracing.Int64("aws.sts.duration_seconds", int64(aws.ToInt32(params.DurationSeconds))),
+		tracing.Bool("aws.sts.external_id", params.ExternalId != nil),
+	)
+	resp, err := c.STSClient.AssumeRole(ctx, params, optFns...)
+	if err != nil {
+		span.SetAttributes(tracing.String("aws.error_code", errorCode(err)))
+	}
+	span.End(err)
+	return resp, err
+}
+
+func (c *stsClientWithTracing) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	ctx, span := c.tracer.Start(ctx, "sts.GetCallerIdentity")
+	resp, err := c.STSClient.GetCallerIdentity(ctx, params, optFns...)
+	span.End(err)
+	return resp, err
+}
@@ -0,0 +1,206 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// the default value of service.name resource attribute.
+	defaultOTLPServiceName = "actions-aws-assume-role"
+
+	// the name of the instrumentation scope.
+	otlpScopeName = "github.com/fuller-inc/actions-aws-assume-role/provider/assume-role"
+)
+
+// OTLPConfig is configure for OTLPTracer.
+type OTLPConfig struct {
+	// Endpoint is the URL of the OTLP/HTTP traces endpoint of the collector.
+	// If it is empty, the exporter reads the standard environment values of OpenTelemetry,
+	// e.g. OTEL_EXPORTER_OTLP_TRACES_ENDPOINT and OTEL_EXPORTER_OTLP_ENDPOINT.
+	// The default is http://localhost:4318/v1/traces.
+	Endpoint string
+
+	// ServiceName is the value of service.name resource attribute.
+	// If it is empty, "actions-aws-assume-role" is used.
+	ServiceName string
+
+	// HTTPClient is used for exporting spans.
+	// If it is nil, the default client of the exporter is used.
+	HTTPClient *http.Client
+
+	// BatchSize is the maximum number of spans in a single export request.
+	// If it is zero, the default of the SDK (512) is used.
+	BatchSize int
+
+	// Interval is the interval of exporting spans in background.
+	// If it is zero, the default of the SDK (5 seconds) is used.
+	Interval time.Duration
+}
+
+// OTLPTracer is a tracer for OpenTelemetry.
+// It exports the spans to the collector in OTLP/HTTP with the exporter of the OpenTelemetry SDK.
+// The spans are exported in background, call Shutdown to export all spans before exiting.
+type OTLPTracer struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewOTLPTracer returns a new tracer for OpenTelemetry.
+// It doesn't connect to the collector until it exports spans.
+func NewOTLPTracer(cfg *OTLPConfig) (*OTLPTracer, error) {
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	}
+	if cfg.HTTPClient != nil {
+		opts = append(opts, otlptracehttp.WithHTTPClient(cfg.HTTPClient))
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: failed to create the otlp exporter: %w", err)
+	}
+
+	var batchOpts []sdktrace.BatchSpanProcessorOption
+	if cfg.BatchSize != 0 {
+		batchOpts = append(batchOpts, sdktrace.WithMaxExportBatchSize(cfg.BatchSize))
+	}
+	if cfg.Interval != 0 {
+		batchOpts = append(batchOpts, sdktrace.WithBatchTimeout(cfg.Interval))
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultOTLPServiceName
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter, batchOpts...),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	return &OTLPTracer{
+		provider:   provider,
+		tracer:     provider.Tracer(otlpScopeName),
+		propagator: propagation.TraceContext{},
+	}, nil
+}
+
+// Flush exports all finished spans.
+func (t *OTLPTracer) Flush(ctx context.Context) error {
+	return t.provider.ForceFlush(ctx)
+}
+
+// Shutdown stops exporting in background, and exports all finished spans.
+func (t *OTLPTracer) Shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
+
+func (t *OTLPTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(otlpAttributes(attrs)...))
+	return ctx, otlpSpan{span: span}
+}
+
+// Extract returns a context with the remote span context in the W3C traceparent header.
+// https://www.w3.org/TR/trace-context/#traceparent-header
+func (t *OTLPTracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return t.propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// HTTPClient returns a client that records client spans and propagates the traceparent header.
+func (t *OTLPTracer) HTTPClient(client *http.Client) *http.Client {
+	var c http.Client
+	if client != nil {
+		c = *client
+	}
+	c.Transport = &otlpTransport{
+		tracer: t,
+		base:   c.Transport,
+	}
+	return &c
+}
+
+func (t *OTLPTracer) AWSConfigOptions() []func(*config.LoadOptions) error {
+	return nil
+}
+
+type otlpSpan struct {
+	span trace.Span
+}
+
+func (s otlpSpan) SetAttributes(attrs ...Attribute) {
+	s.span.SetAttributes(otlpAttributes(attrs)...)
+}
+
+func (s otlpSpan) End(err error) {
+	if err != nil {
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+func otlpAttributes(attrs []Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(attr.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(attr.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(attr.Key, v))
+		default:
+			kvs = append(kvs, attribute.String(attr.Key, attr.String()))
+		}
+	}
+	return kvs
+}
+
+// otlpTransport records client spans and propagates the traceparent header.
+type otlpTransport struct {
+	tracer *OTLPTracer
+	base   http.RoundTripper
+}
+
+func (t *otlpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// the URL may contain secrets in the query, so record only the host and the path.
+	ctx, span := t.tracer.tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	s := otlpSpan{span: span}
+
+	// RoundTrip must not modify the request.
+	req = req.Clone(ctx)
+	t.tracer.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := base.RoundTrip(req)
+	if err == nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		if resp.StatusCode >= 500 {
+			s.End(fmt.Errorf("http status code: %d", resp.StatusCode))
+			return resp, nil
+		}
+	}
+	s.End(err)
+	return resp, err
+}
@@ -0,0 +1,212 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is a stand-in for the OpenTelemetry collector.
+type collector struct {
+	mu    sync.Mutex
+	spans []*tracepb.Span
+	srv   *httptest.Server
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{}
+	c.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/x-protobuf" {
+			t.Errorf("unexpected content type: %q", got)
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var req coltracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(data, &req); err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, rs := range req.ResourceSpans {
+			if got := attributeValue(rs.Resource.Attributes, "service.name"); got.GetStringValue() != "test-service" {
+				t.Errorf("unexpected resource: %v", rs.Resource)
+			}
+			c.mu.Lock()
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+			c.mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		data, _ = proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+		w.Write(data)
+	}))
+	t.Cleanup(c.srv.Close)
+	return c
+}
+
+func attributeValue(attrs []*commonpb.KeyValue, key string) *commonpb.AnyValue {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return nil
+}
+
+func newTestOTLPTracer(t *testing.T, c *collector) *OTLPTracer {
+	t.Helper()
+	tracer, err := NewOTLPTracer(&OTLPConfig{
+		Endpoint:    c.srv.URL + "/v1/traces",
+		ServiceName: "test-service",
+		Interval:    time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracer
+}
+
+func TestOTLPTracer(t *testing.T) {
+	c := newCollector(t)
+	tracer := newTestOTLPTracer(t, c)
+
+	// the remote parent
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := tracer.Extract(context.Background(), header)
+
+	ctx, parent := tracer.Start(ctx, "AssumeRole", String("github.repository", "fuller-inc/actions-aws-assume-role"))
+	_, child := tracer.Start(ctx, "sts.AssumeRole", Bool("aws.sts.external_id", true))
+	child.SetAttributes(Int64("aws.sts.duration_seconds", 900))
+	child.End(errors.New("AccessDenied"))
+	parent.End(nil)
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(c.spans))
+	}
+	gotChild, gotParent := c.spans[0], c.spans[1]
+	if hex.EncodeToString(gotParent.TraceId) != "4bf92f3577b34da6a3ce929d0e0e4736" || !bytes.Equal(gotChild.TraceId, gotParent.TraceId) {
+		t.Errorf("unexpected trace ids: %x, %x", gotParent.TraceId, gotChild.TraceId)
+	}
+	if hex.EncodeToString(gotParent.ParentSpanId) != "00f067aa0ba902b7" {
+		t.Errorf("unexpected parent span id: %x", gotParent.ParentSpanId)
+	}
+	if !bytes.Equal(gotChild.ParentSpanId, gotParent.SpanId) {
+		t.Errorf("unexpected parent span id: want %x, got %x", gotParent.SpanId, gotChild.ParentSpanId)
+	}
+	if gotParent.Name != "AssumeRole" || attributeValue(gotParent.Attributes, "github.repository").GetStringValue() != "fuller-inc/actions-aws-assume-role" {
+		t.Errorf("unexpected span: %v", gotParent)
+	}
+	if gotParent.Status.GetCode() != tracepb.Status_STATUS_CODE_UNSET {
+		t.Errorf("unexpected status: %v", gotParent.Status)
+	}
+	if gotChild.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || gotChild.Status.GetMessage() != "AccessDenied" {
+		t.Errorf("unexpected status: %v", gotChild.Status)
+	}
+	if !attributeValue(gotChild.Attributes, "aws.sts.external_id").GetBoolValue() ||
+		attributeValue(gotChild.Attributes, "aws.sts.duration_seconds").GetIntValue() != 900 {
+		t.Errorf("unexpected attributes: %v", gotChild.Attributes)
+	}
+}
+
+func TestOTLPTracer_HTTPClient(t *testing.T) {
+	c := newCollector(t)
+	tracer := newTestOTLPTracer(t, c)
+
+	var traceParent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get("traceparent")
+	}))
+	defer ts.Close()
+
+	ctx, span := tracer.Start(context.Background(), "GetRepo")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/repos/fuller-inc/actions-aws-assume-role?token=secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tracer.HTTPClient(nil).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	span.End(nil)
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(c.spans))
+	}
+	client := c.spans[0]
+	if want := "00-" + hex.EncodeToString(client.TraceId) + "-" + hex.EncodeToString(client.SpanId) + "-01"; traceParent != want {
+		t.Errorf("unexpected traceparent: want %q, got %q", want, traceParent)
+	}
+	if client.Kind != tracepb.Span_SPAN_KIND_CLIENT {
+		t.Errorf("unexpected kind: %v", client.Kind)
+	}
+	for _, attr := range client.Attributes {
+		if strings.Contains(attr.Value.GetStringValue(), "token=secret") {
+			t.Errorf("the query must not be recorded")
+		}
+	}
+}
+
+func TestOTLPTracer_InvalidTraceParent(t *testing.T) {
+	c := newCollector(t)
+	tracer := newTestOTLPTracer(t, c)
+
+	// the invalid header is ignored, and a new trace is started.
+	header := http.Header{}
+	header.Set("traceparent", "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	ctx := tracer.Extract(context.Background(), header)
+	_, span := tracer.Start(ctx, "AssumeRole")
+	span.End(nil)
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.spans) != 1 {
+		t.Fatalf("want 1 span, got %d", len(c.spans))
+	}
+	if got := c.spans[0]; len(got.ParentSpanId) != 0 {
+		t.Errorf("unexpected parent span id: %x", got.ParentSpanId)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, backend := range []string{BackendXRay, BackendOTLP, BackendNone} {
+		tracer, err := New(backend)
+		if err != nil {
+			t.Errorf("%s: %v", backend, err)
+			continue
+		}
+		Shutdown(context.Background(), tracer)
+	}
+	if _, err := New("zipkin"); err == nil {
+		t.Error("want error, but not")
+	}
+}
@@ -0,0 +1,157 @@
+// Package tracing abstracts the tracing backends of the credential provider.
+// AWS X-Ray, OpenTelemetry (OTLP) and no tracing are supported.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+const (
+	BackendXRay = "xray"
+	BackendOTLP = "otlp"
+	BackendNone = "none"
+)
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a new span that is a child of the span in ctx.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+
+	// Extract returns a context with the remote span context that is propagated by header.
+	Extract(ctx context.Context, header http.Header) context.Context
+
+	// HTTPClient returns a client for outgoing requests.
+	// The backend may trace the requests, but it must not duplicate the spans that the handler records.
+	// If client is nil, a new default client is used.
+	HTTPClient(client *http.Client) *http.Client
+
+	// AWSConfigOptions returns the options for the AWS SDK.
+	// The backend may trace the API calls, but it must not duplicate the spans that the handler records.
+	AWSConfigOptions() []func(*config.LoadOptions) error
+}
+
+// Span is a unit of work.
+type Span interface {
+	// SetAttributes adds attributes to the span.
+	// Never add secrets, such as tokens and credentials.
+	SetAttributes(attrs ...Attribute)
+
+	// End finishes the span. err is recorded if it is not nil.
+	End(err error)
+}
+
+// Attribute is a key-value pair that describes a span.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int64 returns an integer attribute.
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func (a Attribute) String() string {
+	switch v := a.Value.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(a.Value)
+}
+
+// New returns a new tracer for the backend.
+func New(backend string) (Tracer, error) {
+	switch backend {
+	case BackendXRay:
+		return XRay(), nil
+	case BackendOTLP:
+		return NewOTLPTracer(&OTLPConfig{
+			ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+		})
+	case BackendNone:
+		return Noop(), nil
+	}
+	return nil, fmt.Errorf("tracing: unknown backend: %q", backend)
+}
+
+// NewFromEnv returns a new tracer for the backend that is configured by the TRACING_BACKEND environment value.
+// If it is empty, X-Ray is used on AWS Lambda, and no tracing is used elsewhere.
+func NewFromEnv() (Tracer, error) {
+	backend := os.Getenv("TRACING_BACKEND")
+	if backend == "" {
+		if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+			backend = BackendXRay
+		} else {
+			backend = BackendNone
+		}
+	}
+	return New(backend)
+}
+
+// Flush exports the finished spans if the tracer buffers them.
+func Flush(ctx context.Context, t Tracer) error {
+	if f, ok := t.(interface{ Flush(context.Context) error }); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// Shutdown exports the finished spans and stops the tracer if the tracer buffers them.
+func Shutdown(ctx context.Context, t Tracer) error {
+	if s, ok := t.(interface{ Shutdown(context.Context) error }); ok {
+		return s.Shutdown(ctx)
+	}
+	return nil
+}
+
+type noopTracer struct{}
+
+// Noop returns a tracer that does nothing.
+func Noop() Tracer {
+	return noopTracer{}
+}
+
+func (noopTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopTracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return ctx
+}
+
+func (noopTracer) HTTPClient(client *http.Client) *http.Client {
+	if client == nil {
+		return &http.Client{}
+	}
+	return client
+}
+
+func (noopTracer) AWSConfigOptions() []func(*config.LoadOptions) error {
+	return nil
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(attrs ...Attribute) {}
+
+func (noopSpan) End(err error) {}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/shogo82148/aws-xray-yasdk-go/xray"
+)
+
+type xrayTracer struct{}
+
+// XRay returns a tracer for AWS X-Ray.
+// The spans are recorded as subsegments, and the attributes are recorded as annotations.
+// The handler records a span for each GitHub and AWS STS API call,
+// so the HTTP client and the AWS SDK are not instrumented; otherwise each call has nested duplicated subsegments.
+func XRay() Tracer {
+	return xrayTracer{}
+}
+
+func (xrayTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	ctx, seg := xray.BeginSubsegment(ctx, name)
+	span := &xraySpan{seg: seg}
+	span.SetAttributes(attrs...)
+	return ctx, span
+}
+
+// Extract returns ctx as is.
+// AWS Lambda and xrayhttp.Handler propagate the trace header of X-Ray.
+func (xrayTracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return ctx
+}
+
+// HTTPClient returns client as is.
+func (xrayTracer) HTTPClient(client *http.Client) *http.Client {
+	if client == nil {
+		return &http.Client{}
+	}
+	return client
+}
+
+// AWSConfigOptions returns no options.
+func (xrayTracer) AWSConfigOptions() []func(*config.LoadOptions) error {
+	return nil
+}
+
+type xraySpan struct {
+	seg *xray.Segment
+}
+
+// the keys of annotations may contain only alphanumeric characters and underscores.
+var xrayKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+func (s *xraySpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		key := xrayKeyReplacer.Replace(attr.Key)
+		switch v := attr.Value.(type) {
+		case string:
+			s.seg.AddAnnotationString(key, v)
+		case int64:
+			s.seg.AddAnnotationInt64(key, v)
+		case bool:
+			s.seg.AddAnnotationBool(key, v)
+		default:
+			s.seg.AddAnnotationString(key, attr.String())
+		}
+	}
+}
+
+func (s *xraySpan) End(err error) {
+	if err != nil {
+		s.seg.AddError(err)
+	}
+	s.seg.Close()
+}
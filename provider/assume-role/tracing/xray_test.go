@@ -0,0 +1,18 @@
+package tracing
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestXRay_NoInstrumentation(t *testing.T) {
+	// the handler records the spans of API calls, so the clients are not instrumented.
+	tracer := XRay()
+	client := &http.Client{}
+	if got := tracer.HTTPClient(client); got != client || got.Transport != nil {
+		t.Errorf("the client is instrumented: %#v", got)
+	}
+	if opts := tracer.AWSConfigOptions(); len(opts) != 0 {
+		t.Errorf("want no options, got %d", len(opts))
+	}
+}
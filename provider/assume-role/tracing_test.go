@@ -0,0 +1,131 @@
+package assumerole
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/tracing"
+)
+
+// recordingTracer records the names and the attributes of the finished spans.
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	name   string
+	attrs  map[string]string
+	err    error
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attrs ...tracing.Attribute) (context.Context, tracing.Span) {
+	span := &recordingSpan{tracer: t, name: name, attrs: map[string]string{}}
+	span.SetAttributes(attrs...)
+	return ctx, span
+}
+
+func (t *recordingTracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return ctx
+}
+
+func (t *recordingTracer) HTTPClient(client *http.Client) *http.Client {
+	return client
+}
+
+func (t *recordingTracer) AWSConfigOptions() []func(*config.LoadOptions) error {
+	return nil
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...tracing.Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.String()
+	}
+}
+
+func (s *recordingSpan) End(err error) {
+	s.err = err
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
+
+func TestSTSClientWithTracing(t *testing.T) {
+	tracer := &recordingTracer{}
+	c := &stsClientWithTracing{
+		STSClient: &stsClientMock{
+			AssumeRoleFunc: func(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+				if params.ExternalId == nil {
+					return nil, &smithy.GenericAPIError{Code: "AccessDenied"}
+				}
+				return &sts.AssumeRoleOutput{}, nil
+			},
+		},
+		tracer: tracer,
+	}
+
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String("arn:aws:iam::123456789012:role/assume-role-test"),
+		RoleSessionName: aws.String("GitHubActions"),
+		DurationSeconds: aws.Int32(900),
+	}
+	c.AssumeRole(context.Background(), input)
+	input.ExternalId = aws.String("R_kgDOABCDEF")
+	c.AssumeRole(context.Background(), input)
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(tracer.spans))
+	}
+	first, second := tracer.spans[0], tracer.spans[1]
+	if first.name != "sts.AssumeRole" || first.attrs["aws.sts.external_id"] != "false" || first.attrs["aws.error_code"] != "AccessDenied" {
+		t.Errorf("unexpected span: %s %v", first.name, first.attrs)
+	}
+	if first.err == nil {
+		t.Error("want error, but not")
+	}
+	if second.attrs["aws.sts.external_id"] != "true" || second.err != nil {
+		t.Errorf("unexpected span: %s %v", second.name, second.attrs)
+	}
+	if got := second.attrs["aws.iam.role_arn"]; got != "arn:aws:iam::123456789012:role/assume-role-test" {
+		t.Errorf("unexpected role arn: %q", got)
+	}
+	for _, span := range tracer.spans {
+		for key, value := range span.attrs {
+			if value == "R_kgDOABCDEF" {
+				t.Errorf("%s: the external id must not be recorded", key)
+			}
+		}
+	}
+}
+
+func TestGitHubClientWithTracing(t *testing.T) {
+	tracer := &recordingTracer{}
+	c := &githubClientWithTracing{
+		GitHubClient: &githubClientDummy{},
+		tracer:       tracer,
+	}
+	const token = "ghs_dummyGitHubToken"
+	if _, err := c.GetRepo(context.Background(), true, token, "fuller-inc", "actions-aws-assume-role"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("want 1 span, got %d", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.name != "github.GetRepo" || span.attrs["github.repository"] != "fuller-inc/actions-aws-assume-role" {
+		t.Errorf("unexpected span: %s %v", span.name, span.attrs)
+	}
+	for key, value := range span.attrs {
+		if value == token {
+			t.Errorf("%s: the token must not be recorded", key)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/trustpolicy"
+)
+
+// the lifetime of the cache of the principal of the credential provider.
+const principalCacheLifetime = time.Hour
+
+type trustPolicyRequestBody struct {
+	Repository         string `json:"repository"`
+	UseNodeID          bool   `json:"use_node_id"`
+	RoleSessionTagging bool   `json:"role_session_tagging"`
+	Environment        string `json:"environment"`
+
+	// GitHubToken is used for looking up the node id if the credential provider has no GitHub App.
+	GitHubToken string `json:"github_token"`
+}
+
+type trustPolicyResponseBody struct {
+	ExternalID string                `json:"external_id"`
+	Principal  string                `json:"principal"`
+	Policy     *trustpolicy.Document `json:"policy"`
+}
+
+// serveTrustPolicy generates the trust policy of IAM roles for the repository.
+func (h *Handler) serveTrustPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.startSpan(h.extractSpanContext(r), "TrustPolicy")
+	var req trustPolicyRequestBody
+	if err := h.decodeRequest(w, r, &req); err != nil {
+		span.End(err)
+		h.handleError(w, r, err)
+		return
+	}
+
+	resp, err := h.trustPolicy(ctx, &req)
+	span.End(err)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(resp)
+}
+
+func (h *Handler) trustPolicy(ctx context.Context, req *trustPolicyRequestBody) (*trustPolicyResponseBody, error) {
+	owner, repo, err := splitOwnerRepo(req.Repository)
+	if err != nil {
+		return nil, err
+	}
+	if req.Environment != "" && !req.RoleSessionTagging {
+		return nil, &validationError{
+			message: "environment requires role_session_tagging, because the environment is passed as a session tag",
+		}
+	}
+
+	if err := h.checkRateLimit(ctx, req.Repository, ""); err != nil {
+		return nil, err
+	}
+
+	externalID, err := h.repositoryExternalID(ctx, owner, repo, req.UseNodeID, req.GitHubToken)
+	if err != nil {
+		return nil, err
+	}
+
+	principal, err := h.providerPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := trustpolicy.Generate(&trustpolicy.Options{
+		Principal:   principal,
+		ExternalID:  externalID,
+		Tagging:     req.RoleSessionTagging,
+		Environment: sanitizeTagValue(req.Environment),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &trustPolicyResponseBody{
+		ExternalID: externalID,
+		Principal:  principal,
+		Policy:     doc,
+	}, nil
+}
+
+// repositoryExternalID returns the ExternalId that the credential provider uses for the repository.
+// Looking up the node id requires the token of the GitHub App or the token supplied by the caller,
+// so that anonymous callers don't consume the rate limit of the GitHub API.
+func (h *Handler) repositoryExternalID(ctx context.Context, owner, repo string, useNodeID bool, githubToken string) (string, error) {
+	var nodeID string
+	if useNodeID {
+		token := h.githubToken(ctx, owner, repo, &requestBody{GitHubToken: githubToken})
+		if token == "" {
+			return "", &validationError{
+				message: fmt.Sprintf("github_token is required to look up the node id of %s/%s, because the GitHub App of the credential provider is not available", owner, repo),
+			}
+		}
+		resp, err := h.github.GetRepo(ctx, true, token, owner, repo)
+		if err != nil {
+			var githubErr *github.UnexpectedStatusCodeError
+			if errors.As(err, &githubErr) && githubErr.StatusCode == http.StatusNotFound {
+				return "", &validationError{
+					message: fmt.Sprintf("the repository %s/%s is not found", owner, repo),
+				}
+			}
+			return "", err
+		}
+		nodeID = resp.NodeID
+	}
+	return trustpolicy.ExternalID(owner+"/"+repo, nodeID, useNodeID), nil
+}
+
+// providerPrincipal returns the principal of the credential provider for trust policies.
+func (h *Handler) providerPrincipal(ctx context.Context) (string, error) {
+	principal, _, err := h.principalCache.Do(ctx, struct{}{}, func(ctx context.Context, _ struct{}) (string, time.Time, error) {
+		resp, err := h.sts.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		account := aws.ToString(resp.Account)
+		if account == "" {
+			return "", time.Time{}, errors.New("the account of the credential provider is unknown")
+		}
+		return trustpolicy.AccountPrincipal(account), time.Now().Add(principalCacheLifetime), nil
+	})
+	return principal, err
+}
@@ -0,0 +1,177 @@
+package assumerole
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/github"
+	"github.com/fuller-inc/actions-aws-assume-role/provider/assume-role/trustpolicy"
+)
+
+func newTrustPolicyTestHandler(t *testing.T, opts ...Option) *Handler {
+	t.Helper()
+	opts = append([]Option{
+		WithGitHubClient(&githubClientDummy{}),
+		WithSTSClientFactory(func(ctx context.Context) (STSClient, error) {
+			return &stsClientMock{
+				GetCallerIdentityFunc: func(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+					return &sts.GetCallerIdentityOutput{
+						Account: aws.String("053160724612"),
+					}, nil
+				},
+			}, nil
+		}),
+	}, opts...)
+	h, err := NewHandlerWithOptions(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestTrustPolicy(t *testing.T) {
+	h := newTrustPolicyTestHandler(t)
+
+	body := `{"repository":"fuller-inc/actions-aws-assume-role","use_node_id":true,"role_session_tagging":true,"environment":"production","github_token":"ghs_dummyGitHubToken"}`
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/trust-policy", strings.NewReader(body))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		ExternalID string          `json:"external_id"`
+		Principal  string          `json:"principal"`
+		Policy     json.RawMessage `json:"policy"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ExternalID != "R_kgDOFMsDjw" {
+		t.Errorf("unexpected external id: %q", resp.ExternalID)
+	}
+	if resp.Principal != "arn:aws:iam::053160724612:root" {
+		t.Errorf("unexpected principal: %q", resp.Principal)
+	}
+
+	doc, err := trustpolicy.Parse(resp.Policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Statement) != 2 {
+		t.Fatalf("want 2 statements, got %d", len(doc.Statement))
+	}
+	cond := doc.Statement[0].Condition["StringEquals"]
+	if got := cond["sts:ExternalId"]; len(got) != 1 || got[0] != "R_kgDOFMsDjw" {
+		t.Errorf("unexpected external id condition: %v", got)
+	}
+	if got := cond["aws:RequestTag/Environment"]; len(got) != 1 || got[0] != "production" {
+		t.Errorf("unexpected environment condition: %v", got)
+	}
+	if got := doc.Statement[1].Action; len(got) != 1 || got[0] != "sts:TagSession" {
+		t.Errorf("unexpected action: %v", got)
+	}
+}
+
+func TestTrustPolicy_RepositoryName(t *testing.T) {
+	h := newTrustPolicyTestHandler(t)
+	resp, err := h.trustPolicy(context.Background(), &trustPolicyRequestBody{
+		Repository: "fuller-inc/actions-aws-assume-role",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ExternalID != "fuller-inc/actions-aws-assume-role" {
+		t.Errorf("unexpected external id: %q", resp.ExternalID)
+	}
+	if len(resp.Policy.Statement) != 1 {
+		t.Errorf("want 1 statement, got %d", len(resp.Policy.Statement))
+	}
+}
+
+func TestTrustPolicy_Invalid(t *testing.T) {
+	h := newTrustPolicyTestHandler(t)
+	cases := []string{
+		`{"repository":"actions-aws-assume-role"}`,
+		`{"repository":"fuller-inc/actions-aws-assume-role","environment":"production"}`,
+	}
+	for _, body := range cases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/trust-policy", strings.NewReader(body))
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: unexpected status code: %d, body: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestTrustPolicy_GitHubToken(t *testing.T) {
+	var tokens []string
+	client := &githubClientMock{
+		GetRepoFunc: func(ctx context.Context, nextIDFormat bool, token, owner, repo string) (*github.GetRepoResponse, error) {
+			tokens = append(tokens, token)
+			return dummyGetRepoFunc(ctx, nextIDFormat, token, owner, repo)
+		},
+	}
+	req := &trustPolicyRequestBody{
+		Repository: "fuller-inc/actions-aws-assume-role",
+		UseNodeID:  true,
+	}
+
+	// looking up the node id requires a token.
+	h := newTrustPolicyTestHandler(t, WithGitHubClient(client))
+	_, err := h.trustPolicy(context.Background(), req)
+	var validation *validationError
+	if !errors.As(err, &validation) {
+		t.Errorf("want validation error, got %v", err)
+	}
+	if len(tokens) != 0 {
+		t.Errorf("GitHub API is called without tokens: %q", tokens)
+	}
+
+	// the token of the caller is used.
+	req.GitHubToken = "ghs_dummyGitHubToken"
+	if _, err := h.trustPolicy(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	// the token of the GitHub App is preferred.
+	h = newTrustPolicyTestHandler(t, WithGitHubClient(client), WithGitHubTokenSource(staticTokenSource("ghs_dummyAppToken")))
+	if _, err := h.trustPolicy(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"ghs_dummyGitHubToken", "ghs_dummyAppToken"}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("unexpected tokens: want %q, got %q", want, tokens)
+	}
+}
+
+func TestTrustPolicy_RateLimit(t *testing.T) {
+	h := newTrustPolicyTestHandler(t, WithRateLimits(
+		RateLimit{Key: RateLimitKeyRepository, RequestsPerMinute: 1},
+	))
+
+	body := `{"repository":"fuller-inc/actions-aws-assume-role"}`
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/trust-policy", strings.NewReader(body))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/trust-policy", strings.NewReader(body))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status code: %d, body: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,61 @@
+package trustpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+// IAMClient is the interface of AWS IAM API used by FetchRolePolicy.
+// *iam.Client implements it.
+type IAMClient interface {
+	GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+}
+
+var _ IAMClient = (*iam.Client)(nil)
+
+// ErrInvalidRoleARN is returned by FetchRolePolicy if the ARN is not a role ARN.
+var ErrInvalidRoleARN = errors.New("trustpolicy: invalid role arn")
+
+// FetchRolePolicy fetches the trust policy of the role with iam:GetRole.
+func FetchRolePolicy(ctx context.Context, client IAMClient, roleARN string) (*Document, error) {
+	name, err := roleName(roleARN)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.GetRole(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Role == nil {
+		return nil, fmt.Errorf("trustpolicy: the role %s is not found", roleARN)
+	}
+
+	// the document is URL-encoded.
+	data, err := url.QueryUnescape(aws.ToString(resp.Role.AssumeRolePolicyDocument))
+	if err != nil {
+		return nil, fmt.Errorf("trustpolicy: failed to decode the trust policy: %w", err)
+	}
+	return Parse([]byte(data))
+}
+
+// roleName returns the name of the role from its ARN, e.g. "arn:aws:iam::123456789012:role/path/name" -> "name".
+func roleName(roleARN string) (string, error) {
+	a, err := arn.Parse(roleARN)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %v", ErrInvalidRoleARN, roleARN, err)
+	}
+	resource, ok := strings.CutPrefix(a.Resource, "role/")
+	if a.Service != "iam" || !ok {
+		return "", fmt.Errorf("%w %q", ErrInvalidRoleARN, roleARN)
+	}
+	return resource[strings.LastIndex(resource, "/")+1:], nil
+}
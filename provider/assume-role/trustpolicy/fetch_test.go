@@ -0,0 +1,55 @@
+package trustpolicy
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+type iamClientMock struct {
+	GetRoleFunc func(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+}
+
+func (c *iamClientMock) GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	return c.GetRoleFunc(ctx, params, optFns...)
+}
+
+func TestFetchRolePolicy(t *testing.T) {
+	policy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::053160724612:root"},"Action":"sts:AssumeRole"}]}`
+	client := &iamClientMock{
+		GetRoleFunc: func(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+			if aws.ToString(params.RoleName) != "assume-role-test" {
+				t.Errorf("unexpected role name: %q", aws.ToString(params.RoleName))
+			}
+			return &iam.GetRoleOutput{
+				Role: &types.Role{
+					AssumeRolePolicyDocument: aws.String(url.QueryEscape(policy)),
+				},
+			}, nil
+		},
+	}
+	doc, err := FetchRolePolicy(context.Background(), client, "arn:aws:iam::123456789012:role/github/assume-role-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Statement) != 1 || doc.Statement[0].Principal.AWS[0] != "arn:aws:iam::053160724612:root" {
+		t.Errorf("unexpected document: %#v", doc)
+	}
+}
+
+func TestFetchRolePolicy_InvalidARN(t *testing.T) {
+	for _, roleARN := range []string{
+		"assume-role-test",
+		"arn:aws:iam::123456789012:user/assume-role-test",
+		"arn:aws:s3:::role/assume-role-test",
+	} {
+		if _, err := FetchRolePolicy(context.Background(), &iamClientMock{}, roleARN); !errors.Is(err, ErrInvalidRoleARN) {
+			t.Errorf("%s: want ErrInvalidRoleARN, got %v", roleARN, err)
+		}
+	}
+}
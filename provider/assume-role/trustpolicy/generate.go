@@ -0,0 +1,79 @@
+package trustpolicy
+
+import (
+	"errors"
+)
+
+// Options is the options for generating trust policies.
+type Options struct {
+	// Principal is the ARN of the principal of the credential provider,
+	// e.g. "arn:aws:iam::053160724612:root".
+	Principal string
+
+	// ExternalID is the ExternalId that the credential provider uses for the repository.
+	// Use ExternalID to derive it.
+	ExternalID string
+
+	// Tagging allows the credential provider to pass session tags.
+	Tagging bool
+
+	// Environment restricts the GitHub environment that can assume the role.
+	// It requires Tagging, because the environment is passed as a session tag.
+	Environment string
+}
+
+// ExternalID returns the ExternalId that the credential provider uses for the repository.
+// It is the node ID of the repository if useNodeID is true, otherwise the full name of the repository.
+func ExternalID(repository, repositoryNodeID string, useNodeID bool) string {
+	if useNodeID {
+		return repositoryNodeID
+	}
+	return repository
+}
+
+// AccountPrincipal returns the principal of the account.
+func AccountPrincipal(account string) string {
+	return "arn:aws:iam::" + account + ":root"
+}
+
+// Generate generates the trust policy that allows the credential provider to assume the role.
+func Generate(opts *Options) (*Document, error) {
+	if opts.Principal == "" {
+		return nil, errors.New("trustpolicy: principal is required")
+	}
+	if opts.ExternalID == "" {
+		return nil, errors.New("trustpolicy: external id is required")
+	}
+	if opts.Environment != "" && !opts.Tagging {
+		return nil, errors.New("trustpolicy: environment requires tagging")
+	}
+
+	principal := &Principal{AWS: StringList{opts.Principal}}
+	cond := map[string]StringList{
+		"sts:ExternalId": {opts.ExternalID},
+	}
+	if opts.Environment != "" {
+		cond["aws:RequestTag/Environment"] = StringList{opts.Environment}
+	}
+	doc := &Document{
+		Version: Version,
+		Statement: []*Statement{
+			{
+				Effect:    "Allow",
+				Principal: principal,
+				Action:    StringList{"sts:AssumeRole"},
+				Condition: Condition{
+					"StringEquals": cond,
+				},
+			},
+		},
+	}
+	if opts.Tagging {
+		doc.Statement = append(doc.Statement, &Statement{
+			Effect:    "Allow",
+			Principal: principal,
+			Action:    StringList{"sts:TagSession"},
+		})
+	}
+	return doc, nil
+}
@@ -0,0 +1,101 @@
+package trustpolicy
+
+import (
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	doc, err := Generate(&Options{
+		Principal:  AccountPrincipal("053160724612"),
+		ExternalID: ExternalID("fuller-inc/actions-aws-assume-role", "R_kgDOFMsDjw", false),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := doc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {
+        "AWS": "arn:aws:iam::053160724612:root"
+      },
+      "Action": "sts:AssumeRole",
+      "Condition": {
+        "StringEquals": {
+          "sts:ExternalId": "fuller-inc/actions-aws-assume-role"
+        }
+      }
+    }
+  ]
+}`
+	if string(got) != want {
+		t.Errorf("unexpected policy:\nwant: %s\ngot: %s", want, got)
+	}
+}
+
+func TestGenerate_Tagging(t *testing.T) {
+	doc, err := Generate(&Options{
+		Principal:   AccountPrincipal("053160724612"),
+		ExternalID:  ExternalID("fuller-inc/actions-aws-assume-role", "R_kgDOFMsDjw", true),
+		Tagging:     true,
+		Environment: "production",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Statement) != 2 {
+		t.Fatalf("unexpected statements: %d", len(doc.Statement))
+	}
+	cond := doc.Statement[0].Condition["StringEquals"]
+	if got := cond["sts:ExternalId"]; len(got) != 1 || got[0] != "R_kgDOFMsDjw" {
+		t.Errorf("unexpected external id: %v", got)
+	}
+	if got := cond["aws:RequestTag/Environment"]; len(got) != 1 || got[0] != "production" {
+		t.Errorf("unexpected environment: %v", got)
+	}
+	if got := doc.Statement[1].Action; len(got) != 1 || got[0] != "sts:TagSession" {
+		t.Errorf("unexpected action: %v", got)
+	}
+}
+
+func TestGenerate_EnvironmentWithoutTagging(t *testing.T) {
+	_, err := Generate(&Options{
+		Principal:   AccountPrincipal("053160724612"),
+		ExternalID:  "fuller-inc/actions-aws-assume-role",
+		Environment: "production",
+	})
+	if err == nil {
+		t.Error("want error, but not")
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(`{
+		"Version": "2012-10-17",
+		"Statement": [
+			{"Effect": "Allow", "Principal": "*", "Action": ["sts:AssumeRole", "sts:TagSession"]},
+			{"Effect": "Allow", "Principal": {"AWS": ["arn:aws:iam::053160724612:root"]}, "Action": "sts:AssumeRole",
+			 "Condition": {"StringLike": {"sts:ExternalId": ["fuller-inc/*"]}}}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Statement[0].Principal.Any {
+		t.Error("want any principal, but not")
+	}
+	if len(doc.Statement[0].Action) != 2 {
+		t.Errorf("unexpected actions: %v", doc.Statement[0].Action)
+	}
+	if got := doc.Statement[1].Principal.AWS; len(got) != 1 || got[0] != "arn:aws:iam::053160724612:root" {
+		t.Errorf("unexpected principal: %v", got)
+	}
+	if got := doc.Statement[1].Condition["StringLike"]["sts:ExternalId"]; len(got) != 1 || got[0] != "fuller-inc/*" {
+		t.Errorf("unexpected condition: %v", got)
+	}
+}
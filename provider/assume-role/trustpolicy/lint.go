@@ -0,0 +1,191 @@
+package trustpolicy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Severity is the severity of issues.
+type Severity string
+
+const (
+	// SeverityError means that the trust policy is insecure or doesn't work with the credential provider.
+	SeverityError Severity = "error"
+
+	// SeverityWarning means that the trust policy works, but it may be unintended.
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem that Lint finds in trust policies.
+type Issue struct {
+	// Code is a short code of the issue, e.g. "MissingExternalId".
+	Code string `json:"code"`
+
+	Severity Severity `json:"severity"`
+
+	// Statement is the index of the statement that has the issue.
+	// It is -1 if the issue is about the whole document.
+	Statement int `json:"statement"`
+
+	Message string `json:"message"`
+}
+
+// LintOptions is the options for Lint.
+type LintOptions struct {
+	// Principal is the ARN of the principal of the credential provider,
+	// e.g. "arn:aws:iam::053160724612:root".
+	// If it is empty, all statements that allow sts:AssumeRole are checked.
+	Principal string
+
+	// ExternalID is the ExternalId that the credential provider uses for the repository.
+	// If it is empty, any fixed ExternalId is accepted.
+	ExternalID string
+
+	// Tagging reports whether the credential provider passes session tags.
+	Tagging bool
+}
+
+// Lint analyzes the trust policy of the IAM role that the credential provider assumes.
+func Lint(doc *Document, opts *LintOptions) []*Issue {
+	if opts == nil {
+		opts = &LintOptions{}
+	}
+	var issues []*Issue
+	add := func(code string, severity Severity, statement int, format string, args ...any) {
+		issues = append(issues, &Issue{
+			Code:      code,
+			Severity:  severity,
+			Statement: statement,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	var assumeRole, tagSession bool
+	for i, stmt := range doc.Statement {
+		if !strings.EqualFold(stmt.Effect, "Allow") {
+			continue
+		}
+		allowsAssumeRole := allowsAction(stmt, "sts:AssumeRole")
+		allowsTagSession := allowsAction(stmt, "sts:TagSession")
+		if !allowsAssumeRole && !allowsTagSession {
+			continue
+		}
+
+		if hasWildcardPrincipal(stmt.Principal) {
+			add("WildcardPrincipal", SeverityError, i, "the statement allows any principal. Specify the principal of the credential provider.")
+		} else if !matchesPrincipal(stmt.Principal, opts.Principal) {
+			continue
+		}
+
+		if allowsTagSession {
+			tagSession = true
+		}
+		if !allowsAssumeRole {
+			continue
+		}
+		assumeRole = true
+
+		equals, like := externalIDConditions(stmt.Condition)
+		switch {
+		case len(equals) == 0 && len(like) == 0:
+			add("MissingExternalId", SeverityError, i, "the statement allows sts:AssumeRole without any sts:ExternalId condition. Anyone who can use the credential provider can assume the role.")
+			continue
+		case len(equals) == 0:
+			var wildcard bool
+			for _, v := range like {
+				if strings.ContainsAny(v, "*?") {
+					wildcard = true
+				}
+			}
+			if wildcard {
+				add("ExternalIdWildcard", SeverityError, i, "the statement allows sts:AssumeRole with wildcard sts:ExternalId %q. Use StringEquals with the exact ExternalId.", strings.Join(like, ", "))
+				continue
+			}
+			add("ExternalIdStringLike", SeverityWarning, i, "the statement uses StringLike for sts:ExternalId. Use StringEquals instead.")
+			equals = like
+		}
+		if len(equals) > 1 {
+			add("MultipleExternalIds", SeverityWarning, i, "the statement accepts multiple ExternalIds %q. Every repository of them can assume the role.", strings.Join(equals, ", "))
+		}
+		if opts.ExternalID != "" && !contains(equals, opts.ExternalID) {
+			add("ExternalIdMismatch", SeverityError, i, "the statement doesn't accept %q as sts:ExternalId.", opts.ExternalID)
+		}
+	}
+
+	if !assumeRole {
+		if opts.Principal != "" {
+			add("ProviderNotAllowed", SeverityError, -1, "no statement allows %s to assume the role.", opts.Principal)
+		} else {
+			add("ProviderNotAllowed", SeverityError, -1, "no statement allows sts:AssumeRole.")
+		}
+	}
+	if opts.Tagging && !tagSession {
+		add("MissingTagSession", SeverityError, -1, "session tagging is enabled, but no statement allows sts:TagSession.")
+	}
+	return issues
+}
+
+// allowsAction reports whether the statement allows the action.
+// Actions are case-insensitive, and they may contain wildcards.
+func allowsAction(stmt *Statement, action string) bool {
+	action = strings.ToLower(action)
+	for _, pattern := range stmt.Action {
+		// actions don't contain '/', so path.Match works as the wildcard matching of IAM.
+		if ok, _ := path.Match(strings.ToLower(pattern), action); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasWildcardPrincipal(p *Principal) bool {
+	if p == nil {
+		return false
+	}
+	return p.Any || contains(p.AWS, "*")
+}
+
+// matchesPrincipal reports whether the principal contains the credential provider.
+func matchesPrincipal(p *Principal, principal string) bool {
+	if p == nil {
+		return false
+	}
+	if principal == "" {
+		return len(p.AWS) > 0
+	}
+	for _, aws := range p.AWS {
+		if aws == principal || AccountPrincipal(aws) == principal {
+			return true
+		}
+	}
+	return false
+}
+
+// externalIDConditions returns the values of sts:ExternalId conditions.
+// The names of condition keys are case-insensitive.
+func externalIDConditions(cond Condition) (equals, like []string) {
+	for op, kv := range cond {
+		for key, values := range kv {
+			if !strings.EqualFold(key, "sts:ExternalId") {
+				continue
+			}
+			switch op {
+			case "StringEquals":
+				equals = append(equals, values...)
+			case "StringLike":
+				like = append(like, values...)
+			}
+		}
+	}
+	return
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
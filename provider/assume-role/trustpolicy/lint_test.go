@@ -0,0 +1,120 @@
+package trustpolicy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	opts := &LintOptions{
+		Principal:  AccountPrincipal("053160724612"),
+		ExternalID: "R_kgDOFMsDjw",
+		Tagging:    true,
+	}
+	cases := []struct {
+		name   string
+		policy string
+		want   []string
+	}{
+		{
+			name: "generated",
+			policy: `{"Version":"2012-10-17","Statement":[
+				{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::053160724612:root"},"Action":"sts:AssumeRole","Condition":{"StringEquals":{"sts:ExternalId":"R_kgDOFMsDjw"}}},
+				{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::053160724612:root"},"Action":"sts:TagSession"}
+			]}`,
+			want: nil,
+		},
+		{
+			name: "account id principal and wildcard action",
+			policy: `{"Version":"2012-10-17","Statement":[
+				{"Effect":"Allow","Principal":{"AWS":"053160724612"},"Action":["sts:AssumeRole","sts:Tag*"],"Condition":{"StringEquals":{"sts:externalid":"R_kgDOFMsDjw"}}}
+			]}`,
+			want: nil,
+		},
+		{
+			name: "wildcard principal",
+			policy: `{"Version":"2012-10-17","Statement":[
+				{"Effect":"Allow","Principal":"*","Action":["sts:AssumeRole","sts:TagSession"],"Condition":{"StringEquals":{"sts:ExternalId":"R_kgDOFMsDjw"}}}
+			]}`,
+			want: []string{"WildcardPrincipal"},
+		},
+		{
+			name: "missing external id",
+			policy: `{"Version":"2012-10-17","Statement":[
+				{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::053160724612:root"},"Action":["sts:AssumeRole","sts:TagSession"]}
+			]}`,
+			want: []string{"MissingExternalId"},
+		},
+		{
+			name: "string like with wildcard",
+			policy: `{"Version":"2012-10-17","Statement":[
+				{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::053160724612:root"},"Action":["sts:AssumeRole","sts:TagSession"],"Condition":{"StringLike":{"sts:ExternalId":"fuller-inc/*"}}}
+			]}`,
+			want: []string{"ExternalIdWildcard"},
+		},
+		{
+			name: "string like without wildcard",
+			policy: `{"Version":"2012-10-17","Statement":[
+				{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::053160724612:root"},"Action":["sts:AssumeRole","sts:TagSession"],"Condition":{"StringLike":{"sts:ExternalId":"R_kgDOFMsDjw"}}}
+			]}`,
+			want: []string{"ExternalIdStringLike"},
+		},
+		{
+			name: "another repository",
+			policy: `{"Version":"2012-10-17","Statement":[
+				{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::053160724612:root"},"Action":["sts:AssumeRole","sts:TagSession"],"Condition":{"StringEquals":{"sts:ExternalId":["fuller-inc/other","MDEwOlJlcG9zaXRvcnkzNDg4NDkwMzk="]}}}
+			]}`,
+			want: []string{"MultipleExternalIds", "ExternalIdMismatch"},
+		},
+		{
+			name: "missing tag session",
+			policy: `{"Version":"2012-10-17","Statement":[
+				{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::053160724612:root"},"Action":"sts:AssumeRole","Condition":{"StringEquals":{"sts:ExternalId":"R_kgDOFMsDjw"}}}
+			]}`,
+			want: []string{"MissingTagSession"},
+		},
+		{
+			name: "another principal",
+			policy: `{"Version":"2012-10-17","Statement":[
+				{"Effect":"Allow","Principal":{"Federated":"arn:aws:iam::123456789012:oidc-provider/token.actions.githubusercontent.com"},"Action":"sts:AssumeRoleWithWebIdentity"},
+				{"Effect":"Deny","Principal":{"AWS":"arn:aws:iam::053160724612:root"},"Action":"sts:AssumeRole"}
+			]}`,
+			want: []string{"ProviderNotAllowed", "MissingTagSession"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tc.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, issue := range Lint(doc, opts) {
+				got = append(got, issue.Code)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLint_Generated(t *testing.T) {
+	doc, err := Generate(&Options{
+		Principal:   AccountPrincipal("053160724612"),
+		ExternalID:  "R_kgDOFMsDjw",
+		Tagging:     true,
+		Environment: "production",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := Lint(doc, &LintOptions{
+		Principal:  AccountPrincipal("053160724612"),
+		ExternalID: "R_kgDOFMsDjw",
+		Tagging:    true,
+	})
+	if len(issues) != 0 {
+		t.Errorf("the generated policy has issues: %v", issues)
+	}
+}
@@ -0,0 +1,141 @@
+// Package trustpolicy generates the trust policies of IAM roles for the credential provider.
+package trustpolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// Version is the version of the policy language.
+const Version = "2012-10-17"
+
+// Document is an IAM policy document.
+type Document struct {
+	Version   string       `json:"Version"`
+	Statement []*Statement `json:"Statement"`
+}
+
+// Statement is a statement of IAM policy documents.
+type Statement struct {
+	Sid       string     `json:"Sid,omitempty"`
+	Effect    string     `json:"Effect"`
+	Principal *Principal `json:"Principal,omitempty"`
+	Action    StringList `json:"Action,omitempty"`
+	Condition Condition  `json:"Condition,omitempty"`
+}
+
+// Condition is the condition block of statements.
+// The key of the outer map is the operator, e.g. "StringEquals",
+// and the key of the inner map is the condition key, e.g. "sts:ExternalId".
+type Condition map[string]map[string]StringList
+
+// Principal is the principal of statements.
+type Principal struct {
+	// Any is true if the principal is "*".
+	Any bool
+
+	AWS       StringList
+	Service   StringList
+	Federated StringList
+}
+
+type principalJSON struct {
+	AWS       StringList `json:"AWS,omitempty"`
+	Service   StringList `json:"Service,omitempty"`
+	Federated StringList `json:"Federated,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p *Principal) MarshalJSON() ([]byte, error) {
+	if p.Any {
+		return []byte(`"*"`), nil
+	}
+	return marshal(principalJSON{
+		AWS:       p.AWS,
+		Service:   p.Service,
+		Federated: p.Federated,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if s != "*" {
+			return errors.New("trustpolicy: invalid principal: " + s)
+		}
+		*p = Principal{Any: true}
+		return nil
+	}
+	var v principalJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*p = Principal{
+		AWS:       v.AWS,
+		Service:   v.Service,
+		Federated: v.Federated,
+	}
+	return nil
+}
+
+// StringList is a list of strings.
+// In JSON, it is a string if it has only one element, otherwise an array of strings.
+type StringList []string
+
+// MarshalJSON implements json.Marshaler.
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return marshal(l[0])
+	}
+	return marshal([]string(l))
+}
+
+// marshal is same as json.Marshal, but it doesn't escape HTML characters.
+// Placeholders such as "<account>" are kept readable.
+func marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = StringList{s}
+		return nil
+	}
+	var v []string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+// Parse parses the policy document.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Marshal returns the indented JSON encoding of the document.
+func (doc *Document) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,262 @@
+package assumerole
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookEventBeforeIssue = "before_issue"
+	webhookEventAfterIssue  = "after_issue"
+
+	// the default timeout of webhook requests.
+	defaultWebhookTimeout = 5 * time.Second
+
+	// the header for the signature of the payload.
+	webhookSignatureHeader = "X-Assume-Role-Signature-256"
+)
+
+// WebhookHookConfig is configure for WebhookHook.
+type WebhookHookConfig struct {
+	// URL is the endpoint of the webhook.
+	URL string
+
+	// Secret is the secret key for signing payloads.
+	// If it is not empty, the X-Assume-Role-Signature-256 header has the HMAC-SHA256 signature of the payload,
+	// in the same format as the X-Hub-Signature-256 header of GitHub webhooks.
+	Secret []byte
+
+	// HTTPClient is used for webhook requests.
+	// If it is nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// Timeout is the timeout of each webhook request.
+	// If it is zero, 5 seconds is used.
+	Timeout time.Duration
+
+	// FailOpen allows issuing credentials if the webhook is unavailable.
+	// By default, the request is rejected.
+	FailOpen bool
+
+	// Logger is used for logging failures of the webhook.
+	// If it is nil, slog.Default() is used.
+	Logger *slog.Logger
+}
+
+// WebhookHook is a Hook that sends issuances to an HTTP endpoint.
+//
+// Before issuing credentials, it posts the issuance with the "before_issue" event.
+// The endpoint responds with a JSON object like {"allow": false, "message": "change freeze", "warning": "..."}.
+// If allow is false, the request is rejected with the message.
+// The warning is shown in the log of the workflow.
+//
+// After issuing credentials, it posts the issuance and the result with the "after_issue" event.
+// The response is ignored.
+type WebhookHook struct {
+	url        string
+	secret     []byte
+	httpClient *http.Client
+	timeout    time.Duration
+	failOpen   bool
+	logger     *slog.Logger
+}
+
+var _ Hook = (*WebhookHook)(nil)
+
+// NewWebhookHook returns a new WebhookHook.
+func NewWebhookHook(cfg *WebhookHookConfig) (*WebhookHook, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("webhook: url is required")
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &WebhookHook{
+		url:        cfg.URL,
+		secret:     cfg.Secret,
+		httpClient: client,
+		timeout:    timeout,
+		failOpen:   cfg.FailOpen,
+		logger:     cfg.Logger,
+	}, nil
+}
+
+type webhookPayload struct {
+	Event    string          `json:"event"`
+	Issuance webhookIssuance `json:"issuance"`
+	Result   *webhookResult  `json:"result,omitempty"`
+}
+
+// webhookIssuance is the issuance in webhook payloads.
+// It never contains tokens and credentials.
+type webhookIssuance struct {
+	Repository      string         `json:"repository"`
+	RoleARN         string         `json:"role_arn"`
+	RoleSessionName string         `json:"role_session_name"`
+	DurationSeconds int32          `json:"duration_seconds"`
+	Claims          *webhookClaims `json:"claims,omitempty"`
+}
+
+type webhookClaims struct {
+	Subject         string `json:"sub"`
+	Repository      string `json:"repository"`
+	RepositoryOwner string `json:"repository_owner"`
+	Ref             string `json:"ref"`
+	SHA             string `json:"sha"`
+	Environment     string `json:"environment,omitempty"`
+	Workflow        string `json:"workflow"`
+	JobWorkflowRef  string `json:"job_workflow_ref"`
+	EventName       string `json:"event_name"`
+	RunID           string `json:"run_id"`
+	RunAttempt      string `json:"run_attempt"`
+	Actor           string `json:"actor"`
+}
+
+type webhookResult struct {
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Expiration time.Time `json:"expiration,omitzero"`
+}
+
+type webhookResponse struct {
+	Allow   *bool  `json:"allow"`
+	Message string `json:"message"`
+	Warning string `json:"warning"`
+}
+
+func newWebhookIssuance(issuance *Issuance) webhookIssuance {
+	ret := webhookIssuance{
+		Repository:      issuance.Repository,
+		RoleARN:         issuance.RoleARN,
+		RoleSessionName: issuance.RoleSessionName,
+		DurationSeconds: issuance.DurationSeconds,
+	}
+	if token := issuance.IDToken; token != nil {
+		ret.Claims = &webhookClaims{
+			Repository:      token.Repository,
+			RepositoryOwner: token.RepositoryOwner,
+			Ref:             token.Ref,
+			SHA:             token.SHA,
+			Environment:     token.Environment,
+			Workflow:        token.Workflow,
+			JobWorkflowRef:  token.JobWorkflowRef,
+			EventName:       token.EventName,
+			RunID:           token.RunID,
+			RunAttempt:      token.RunAttempt,
+			Actor:           token.Actor,
+		}
+		if token.Claims != nil {
+			ret.Claims.Subject = token.Subject
+		}
+	}
+	return ret
+}
+
+// BeforeIssue implements Hook.
+func (hook *WebhookHook) BeforeIssue(ctx context.Context, issuance *Issuance) error {
+	resp, err := hook.post(ctx, &webhookPayload{
+		Event:    webhookEventBeforeIssue,
+		Issuance: newWebhookIssuance(issuance),
+	})
+	if err != nil {
+		if hook.failOpen {
+			hook.log().WarnContext(ctx, "the webhook is unavailable, but the request is allowed", slog.String("error", err.Error()))
+			return nil
+		}
+		return err
+	}
+
+	if resp.Warning != "" {
+		issuance.Warn(resp.Warning)
+	}
+	if resp.Allow == nil || !*resp.Allow {
+		msg := resp.Message
+		if msg == "" {
+			msg = "The request is rejected by the webhook."
+		}
+		return &VetoError{Message: msg}
+	}
+	return nil
+}
+
+// AfterIssue implements Hook.
+func (hook *WebhookHook) AfterIssue(ctx context.Context, issuance *Issuance, result *IssueResult) {
+	r := &webhookResult{
+		Success:    result.Err == nil,
+		Expiration: result.Expiration,
+	}
+	if result.Err != nil {
+		r.Error = result.Err.Error()
+	}
+	if _, err := hook.post(ctx, &webhookPayload{
+		Event:    webhookEventAfterIssue,
+		Issuance: newWebhookIssuance(issuance),
+		Result:   r,
+	}); err != nil {
+		hook.log().WarnContext(ctx, "failed to send the webhook", slog.String("error", err.Error()))
+	}
+}
+
+func (hook *WebhookHook) post(ctx context.Context, payload *webhookPayload) (*webhookResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, hook.timeout)
+	defer cancel()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Assume-Role-Event", payload.Event)
+	if len(hook.secret) > 0 {
+		mac := hmac.New(sha256.New, hook.secret)
+		mac.Write(data)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := hook.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: failed to send the request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("webhook: unexpected status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("webhook: failed to read the response: %w", err)
+	}
+	var ret webhookResponse
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &ret); err != nil {
+			return nil, fmt.Errorf("webhook: failed to parse the response: %w", err)
+		}
+	}
+	return &ret, nil
+}
+
+func (hook *WebhookHook) log() *slog.Logger {
+	if hook.logger == nil {
+		return slog.Default()
+	}
+	return hook.logger
+}
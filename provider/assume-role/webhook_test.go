@@ -0,0 +1,169 @@
+package assumerole
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newWebhookTestServer(t *testing.T, handler func(t *testing.T, payload *webhookPayload) (int, string)) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		// verify the signature
+		mac := hmac.New(sha256.New, []byte("webhook-secret"))
+		mac.Write(data)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if got := r.Header.Get(webhookSignatureHeader); !hmac.Equal([]byte(got), []byte(want)) {
+			t.Errorf("invalid signature: %q", got)
+		}
+
+		var payload webhookPayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			t.Error(err)
+			return
+		}
+		if got := r.Header.Get("X-Assume-Role-Event"); got != payload.Event {
+			t.Errorf("unexpected event header: %q", got)
+		}
+		status, body := handler(t, &payload)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestIssuance() *Issuance {
+	return &Issuance{
+		IDToken:         dummyIDToken(),
+		Repository:      "fuller-inc/actions-aws-assume-role",
+		RoleARN:         "arn:aws:iam::123456789012:role/assume-role-test",
+		RoleSessionName: "GitHubActions",
+		DurationSeconds: 900,
+	}
+}
+
+func TestWebhookHook_BeforeIssue(t *testing.T) {
+	ts := newWebhookTestServer(t, func(t *testing.T, payload *webhookPayload) (int, string) {
+		if payload.Event != webhookEventBeforeIssue {
+			t.Errorf("unexpected event: %q", payload.Event)
+		}
+		if payload.Issuance.RoleARN != "arn:aws:iam::123456789012:role/assume-role-test" {
+			t.Errorf("unexpected role: %q", payload.Issuance.RoleARN)
+		}
+		if payload.Issuance.Claims == nil || payload.Issuance.Claims.Ref != "refs/heads/main" {
+			t.Errorf("unexpected claims: %#v", payload.Issuance.Claims)
+		}
+		return http.StatusOK, `{"allow": true, "warning": "the change freeze starts tomorrow"}`
+	})
+
+	hook, err := NewWebhookHook(&WebhookHookConfig{
+		URL:    ts.URL,
+		Secret: []byte("webhook-secret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuance := newTestIssuance()
+	if err := hook.BeforeIssue(context.Background(), issuance); err != nil {
+		t.Fatal(err)
+	}
+	if got := issuance.warning(); got != "the change freeze starts tomorrow\n" {
+		t.Errorf("unexpected warning: %q", got)
+	}
+}
+
+func TestWebhookHook_Veto(t *testing.T) {
+	ts := newWebhookTestServer(t, func(t *testing.T, payload *webhookPayload) (int, string) {
+		return http.StatusOK, `{"allow": false, "message": "production is frozen until Monday"}`
+	})
+
+	hook, err := NewWebhookHook(&WebhookHookConfig{
+		URL:    ts.URL,
+		Secret: []byte("webhook-secret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = hook.BeforeIssue(context.Background(), newTestIssuance())
+	var veto *VetoError
+	if !errors.As(err, &veto) {
+		t.Fatalf("want VetoError, got %v", err)
+	}
+	if veto.Message != "production is frozen until Monday" {
+		t.Errorf("unexpected message: %q", veto.Message)
+	}
+}
+
+func TestWebhookHook_Unavailable(t *testing.T) {
+	ts := newWebhookTestServer(t, func(t *testing.T, payload *webhookPayload) (int, string) {
+		return http.StatusBadGateway, ``
+	})
+
+	// fail closed by default
+	hook, err := NewWebhookHook(&WebhookHookConfig{
+		URL:    ts.URL,
+		Secret: []byte("webhook-secret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hook.BeforeIssue(context.Background(), newTestIssuance()); err == nil {
+		t.Error("want error, but not")
+	}
+
+	// fail open
+	hook, err = NewWebhookHook(&WebhookHookConfig{
+		URL:      ts.URL,
+		Secret:   []byte("webhook-secret"),
+		FailOpen: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hook.BeforeIssue(context.Background(), newTestIssuance()); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+}
+
+func TestWebhookHook_AfterIssue(t *testing.T) {
+	expiration := time.Date(2026, 10, 19, 1, 0, 0, 0, time.UTC)
+	called := false
+	ts := newWebhookTestServer(t, func(t *testing.T, payload *webhookPayload) (int, string) {
+		called = true
+		if payload.Event != webhookEventAfterIssue {
+			t.Errorf("unexpected event: %q", payload.Event)
+		}
+		if payload.Result == nil || !payload.Result.Success || !payload.Result.Expiration.Equal(expiration) {
+			t.Errorf("unexpected result: %#v", payload.Result)
+		}
+		return http.StatusNoContent, ``
+	})
+
+	hook, err := NewWebhookHook(&WebhookHookConfig{
+		URL:    ts.URL,
+		Secret: []byte("webhook-secret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook.AfterIssue(context.Background(), newTestIssuance(), &IssueResult{Expiration: expiration})
+	if !called {
+		t.Error("the webhook is not called")
+	}
+}